@@ -1,19 +1,178 @@
 package main
 
 import (
+	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/negrel/tabp/pkg/tabp"
 )
 
+// exprsFlag is a flag.Value that collects -e expressions.
+type exprsFlag []string
+
+// String implements flag.Value.
+func (ef *exprsFlag) String() string {
+	return strings.Join(*ef, " ")
+}
+
+// Set implements flag.Value.
+func (ef *exprsFlag) Set(expr string) error {
+	*ef = append(*ef, expr)
+	return nil
+}
+
+func usage(flags *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(flags.Output(), `Usage: %v [-e EXPR]... [FILE]... [-- ARG...]
+
+Evaluates tabp FILEs in order within a shared environment, followed by EXPRs.
+If no FILE and no EXPR are provided, program is read from standard input.
+ARGs following "--" are exposed to the program in the ARGV table, other
+arguments are FILEs.
+
+Example:
+  %v examples/fib/main.tap -- 10
+
+Options:
+`, flags.Name(), flags.Name())
+		flags.PrintDefaults()
+	}
+}
+
 func main() {
-	val := tabp.Eval(os.Stdin)
-	if val != nil {
-		if err, isErr := val.(error); isErr {
-			fmt.Fprintln(os.Stderr, err.Error())
-			return
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs tabp command with the given arguments and standard streams. It
+// returns process exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	args, argv := splitArgs(args)
+
+	flags := flag.NewFlagSet("tabp", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = usage(flags)
+
+	var exprs exprsFlag
+	flags.Var(&exprs, "e", "evaluate `EXPR` and print its result (may be repeated)")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
 		}
-		fmt.Println(tabp.Sexpr(val))
+		return 2
+	}
+	files := flags.Args()
+
+	// Program, REPL and READ-LINE share the same buffered reader so no input
+	// is lost between them.
+	in := bufio.NewReader(stdin)
+
+	rt := tabp.NewRuntime()
+	rt.Env().SetStdin(in)
+	rt.Env().SetStdout(stdout)
+	rt.Env().SetStderr(stderr)
+	rt.Env().Defvar("ARGV", argvTable(argv))
+
+	// Read program from stdin.
+	if len(files) == 0 && len(exprs) == 0 {
+		if f, isFile := stdin.(*os.File); isFile && isTerminal(f) {
+			repl(rt, in, stdout, stderr)
+			return 0
+		}
+
+		files = append(files, "-")
 	}
+
+	// Check files before evaluating any of them, program arguments may have
+	// been passed without "--".
+	for _, fpath := range files {
+		if fpath == "-" {
+			continue
+		}
+
+		if _, err := os.Stat(fpath); err != nil {
+			fmt.Fprintln(stderr, err.Error())
+			if errors.Is(err, fs.ErrNotExist) && len(argv) == 0 {
+				fmt.Fprintln(stderr, `hint: arguments following "--" are passed to the program in ARGV`)
+			}
+			return 1
+		}
+	}
+
+	for _, fpath := range files {
+		err := evalFile(rt, fpath, in)
+		if err != nil {
+			fmt.Fprintln(stderr, errorTraceback(err))
+			return 1
+		}
+	}
+
+	for _, expr := range exprs {
+		result, err := rt.EvalSource("-e", strings.NewReader(expr))
+		if err != nil {
+			fmt.Fprintln(stderr, errorTraceback(err))
+			return 1
+		}
+
+		if result != nil {
+			fmt.Fprintln(stdout, tabp.Sexpr(result))
+		}
+	}
+
+	return 0
+}
+
+// splitArgs splits command line arguments on the first "--".
+func splitArgs(args []string) ([]string, []string) {
+	i := slices.Index(args, "--")
+	if i == -1 {
+		return args, nil
+	}
+
+	return args[:i], args[i+1:]
+}
+
+// argvTable returns a table containing the given arguments as a sequence.
+func argvTable(argv []string) *tabp.Table {
+	var tab tabp.Table
+	for _, arg := range argv {
+		tab.Append(arg)
+	}
+
+	return &tab
+}
+
+// evalFile reads and evaluates tabp program stored at the given path. "-"
+// refers to stdin.
+func evalFile(rt *tabp.Runtime, fpath string, stdin io.Reader) error {
+	if fpath == "-" {
+		_, err := rt.EvalSource("<stdin>", stdin)
+		return err
+	}
+
+	f, err := os.Open(fpath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = rt.EvalSource(fpath, f)
+	return err
+}
+
+// errorTraceback returns message of err followed by its call stack if it is
+// an evaluation error.
+func errorTraceback(err error) string {
+	var evalErr tabp.EvalError
+	if errors.As(err, &evalErr) {
+		return evalErr.Traceback()
+	}
+
+	return err.Error()
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// runTest runs tabp command and returns its exit code, stdout and stderr.
+func runTest(t *testing.T, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+// writeFile writes a tabp program in a temporary directory and returns its
+// path.
+func writeFile(t *testing.T, name, program string) string {
+	fpath := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(fpath, []byte(program), 0o600))
+
+	return fpath
+}
+
+func TestRun(t *testing.T) {
+	t.Run("Files", func(t *testing.T) {
+		lib := writeFile(t, "lib.tap", `(defun double (n) (mul n 2))`)
+		main := writeFile(t, "main.tap", `(printf "%v\n" (double 21))`)
+
+		code, stdout, stderr := runTest(t, "", lib, main)
+		require.Equal(t, 0, code, stderr)
+		require.Equal(t, "42\n", stdout)
+	})
+
+	t.Run("Argv", func(t *testing.T) {
+		main := writeFile(t, "main.tap", `(printf "%v\n" argv)`)
+
+		code, stdout, stderr := runTest(t, "", main, "--", "10", "-e", "--")
+		require.Equal(t, 0, code, stderr)
+		require.Equal(t, `("10" "-e" "--")`+"\n", stdout)
+	})
+
+	t.Run("Fib", func(t *testing.T) {
+		code, stdout, stderr := runTest(t, "", "../../examples/fib/main.tap", "--", "10")
+		require.Equal(t, 0, code, stderr)
+		require.Equal(t, "fib(10) = 55\n", stdout)
+	})
+
+	t.Run("MissingSeparator", func(t *testing.T) {
+		code, stdout, stderr := runTest(t, "", "../../examples/fib/main.tap", "10")
+		require.Equal(t, 1, code)
+		// Program isn't evaluated.
+		require.Empty(t, stdout)
+		require.Contains(t, stderr, `hint: arguments following "--" are passed to the program in ARGV`)
+	})
+
+	t.Run("Exprs", func(t *testing.T) {
+		code, stdout, stderr := runTest(t, "", "-e", "(defvar x 2)", "-e", "(add x (seqlen argv))", "--", "a")
+		require.Equal(t, 0, code, stderr)
+		require.Equal(t, "X\n3\n", stdout)
+	})
+
+	t.Run("Stdin", func(t *testing.T) {
+		code, stdout, stderr := runTest(t, "(printf \"%v\" (add 1 2))")
+		require.Equal(t, 0, code, stderr)
+		require.Equal(t, "3", stdout)
+	})
+
+	t.Run("ReadLine", func(t *testing.T) {
+		main := writeFile(t, "main.tap", `(printf "hello %v" (read-line))`)
+
+		code, stdout, stderr := runTest(t, "world\n", main)
+		require.Equal(t, 0, code, stderr)
+		require.Equal(t, `hello "world"`, stdout)
+
+		code, stdout, stderr = runTest(t, "world\n", "-e", "(read-line)")
+		require.Equal(t, 0, code, stderr)
+		require.Equal(t, "\"world\"\n", stdout)
+	})
+
+	t.Run("EvalError", func(t *testing.T) {
+		main := writeFile(t, "main.tap", "(defun f () (div 1 0))\n(f)")
+
+		code, _, stderr := runTest(t, "", main)
+		require.Equal(t, 1, code)
+		require.Contains(t, stderr, "division by zero")
+		require.Contains(t, stderr, "Traceback (most recent call first):")
+	})
+
+	t.Run("ParseError", func(t *testing.T) {
+		code, _, stderr := runTest(t, "", "-e", "(add 1")
+		require.Equal(t, 1, code)
+		require.Contains(t, stderr, "failed to parse")
+	})
+}
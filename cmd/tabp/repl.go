@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/negrel/tabp/pkg/tabp"
+)
+
+const (
+	replPrompt             = "tabp> "
+	replContinuationPrompt = "...   "
+)
+
+// isTerminal returns whether the given file is a character device (e.g. a
+// terminal).
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+// repl starts an interactive Read-Eval-Print-Loop that reads from r and
+// writes results to stdout and errors to stderr. All expressions are evaluated
+// within rt.
+func repl(rt *tabp.Runtime, r io.Reader, stdout, stderr io.Writer) {
+	reader := bufio.NewReader(r)
+
+	var input strings.Builder
+	for {
+		if input.Len() == 0 {
+			fmt.Fprint(stdout, replPrompt)
+		} else {
+			fmt.Fprint(stdout, replContinuationPrompt)
+		}
+
+		line, err := reader.ReadString('\n')
+		input.WriteString(line)
+		if err != nil {
+			// Ctrl-D.
+			fmt.Fprintln(stdout)
+			return
+		}
+
+		values, parseErr := replParse(input.String())
+		if errors.Is(parseErr, io.ErrUnexpectedEOF) {
+			// Wait for more input.
+			continue
+		}
+		input.Reset()
+
+		if parseErr != nil {
+			fmt.Fprintln(stderr, parseErr.Error())
+			continue
+		}
+
+		for _, v := range values {
+			result, err := rt.EvalValue(v)
+			if err != nil {
+				fmt.Fprintln(stderr, errorTraceback(err))
+				break
+			}
+
+			fmt.Fprintln(stdout, tabp.Sexpr(result))
+		}
+	}
+}
+
+// replParse parses all expressions contained in input. An error wrapping
+// io.ErrUnexpectedEOF is returned if input contains an unfinished expression.
+func replParse(input string) ([]tabp.Value, error) {
+	parser := tabp.NewParser(strings.NewReader(input))
+	parser.SetSource("<repl>")
+
+	var values []tabp.Value
+	for {
+		v, err := parser.Parse()
+		if err.Cause != nil {
+			if err.Cause == io.EOF {
+				return values, nil
+			}
+			return nil, err
+		}
+
+		values = append(values, v)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/negrel/tabp/pkg/tabp"
+	"github.com/stretchr/testify/require"
+)
+
+// replTest runs a REPL session reading the given input and returns its
+// stdout and stderr.
+func replTest(t *testing.T, input string) (string, string) {
+	t.Helper()
+
+	var stdout, stderr strings.Builder
+	rt := tabp.NewRuntime()
+	rt.Env().SetStdout(&stdout)
+	rt.Env().SetStderr(&stderr)
+	repl(rt, strings.NewReader(input), &stdout, &stderr)
+
+	return stdout.String(), stderr.String()
+}
+
+func TestRepl(t *testing.T) {
+	t.Run("Expr", func(t *testing.T) {
+		stdout, stderr := replTest(t, "(add 1 2)\n")
+		require.Empty(t, stderr)
+		require.Equal(t, "tabp> 3\ntabp> \n", stdout)
+	})
+
+	t.Run("MultiLine", func(t *testing.T) {
+		stdout, stderr := replTest(t, "(add 1\n2\n3)\n")
+		require.Empty(t, stderr)
+		require.Equal(t, "tabp> ...   ...   6\ntabp> \n", stdout)
+	})
+
+	t.Run("KeepState", func(t *testing.T) {
+		stdout, stderr := replTest(t, "(defvar x 1)\n(setq x (add x 1))\nx\n")
+		require.Empty(t, stderr)
+		require.Equal(t, "tabp> X\ntabp> 2\ntabp> 2\ntabp> \n", stdout)
+	})
+
+	t.Run("EvalError", func(t *testing.T) {
+		stdout, stderr := replTest(t, "(div 1 0)\n(add 1 2)\n")
+		require.Contains(t, stderr, "division by zero")
+		require.Equal(t, "tabp> tabp> 3\ntabp> \n", stdout)
+	})
+
+	t.Run("ParseError", func(t *testing.T) {
+		stdout, stderr := replTest(t, "99999999999999999999\n(add 1 2)\n")
+		require.Contains(t, stderr, "failed to parse")
+		require.Equal(t, "tabp> tabp> 3\ntabp> \n", stdout)
+	})
+}
@@ -24,6 +24,17 @@ func (at *ArgsTable) consumeArg(name Symbol) Value {
 	return v
 }
 
+// consumeRest returns remaining sequence arguments as a new table.
+func (at *ArgsTable) consumeRest() *Table {
+	var rest Table
+	for at.seqStart+1 < at.tab.SeqLen() {
+		at.seqStart++
+		rest.Append(at.tab.Get(at.seqStart))
+	}
+
+	return &rest
+}
+
 // ToSExpr implements SExpr.
 func (at ArgsTable) ToSExpr() string {
 	return at.tab.ToSExpr()
@@ -1,57 +1,197 @@
 package tabp
 
-import "fmt"
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
 
 // Env define tabp execution environment.
+//
+// Environments are lexically scoped. Each environment holds its own functions,
+// macros and variables and falls back to its parent when a name isn't found.
+// User defined functions (DEFUN and LAMBDA) capture the environment they're
+// defined in. Their body is evaluated within a new child environment of it
+// that holds function parameters, so bindings of the caller are never visible
+// to the callee.
 type Env struct {
 	parent *Env
 	funcs  map[Symbol]func(*Env, ReadOnlyTable) Value
 	macros map[Symbol]func(*Env, ReadOnlyTable) Value
-	vars   map[Symbol]Value
-	// True if env is dedicated to function execution.
-	isFuncEnv bool
+	// Expanders of macros defined using DEFMACRO.
+	expanders map[Symbol]func(ReadOnlyTable) Value
+	vars      map[Symbol]Value
+	state     *envState
+}
+
+// envState holds state shared by an environment and all its descendants.
+type envState struct {
+	// Call stack, outermost call first.
+	frames []StackFrame
+	// Standard streams used by I/O builtins.
+	stdout, stderr io.Writer
+	stdin          *bufio.Reader
+	// Execution limits of current evaluation, ctx is nil if there is none.
+	ctx    context.Context
+	limits Limits
+	steps  int
+	bytes  int
+	// Number of nested trampolines.
+	depth int
+	// Error that aborted current evaluation.
+	abort error
+}
+
+// StackFrame define a function call of a call stack.
+type StackFrame struct {
+	Function Symbol
+	// Position of the call expression, invalid if unknown.
+	Position Position
 }
 
 // EvalError define errors returned when evaluating a Tabp S-Expression.
 type EvalError struct {
 	Cause error
 	Expr  Value
+	// Position of Expr, invalid if unknown.
+	Position Position
+	// Call stack when error occurred, innermost call first.
+	Stack []StackFrame
 }
 
 // Error implements error.
 func (ee EvalError) Error() string {
+	if ee.Position.IsValid() {
+		return fmt.Sprintf("failed to evaluate expression %q at %v: %v", Sexpr(ee.Expr), ee.Position, ee.Cause)
+	}
+
 	return fmt.Sprintf("failed to evaluate expression %q: %v", Sexpr(ee.Expr), ee.Cause)
 }
 
-// NewEnv creates and returns a new blank environment.
+// Traceback returns error message followed by its call stack.
+func (ee EvalError) Traceback() string {
+	var sb strings.Builder
+	sb.WriteString(ee.Error())
+
+	if len(ee.Stack) > 0 {
+		sb.WriteString("\nTraceback (most recent call first):")
+		for _, frame := range ee.Stack {
+			if frame.Position.IsValid() {
+				fmt.Fprintf(&sb, "\n  %v called at %v", frame.Function, frame.Position)
+			} else {
+				fmt.Fprintf(&sb, "\n  %v", frame.Function)
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// Unwrap returns underlying cause of this error.
+func (ee EvalError) Unwrap() error {
+	return ee.Cause
+}
+
+// NewEnv creates and returns a new blank environment. Call stack, standard
+// streams and execution limits are shared with parent environment.
 func NewEnv(parent *Env) Env {
+	var state *envState
+	if parent != nil {
+		state = parent.state
+	}
+	if state == nil {
+		state = &envState{
+			stdout: os.Stdout,
+			stderr: os.Stderr,
+			stdin:  bufio.NewReader(os.Stdin),
+		}
+	}
+
 	return Env{
-		parent:    parent,
-		funcs:     map[Symbol]func(*Env, ReadOnlyTable) Value{},
-		macros:    map[Symbol]func(*Env, ReadOnlyTable) Value{},
-		vars:      map[Symbol]Value{},
-		isFuncEnv: false,
+		parent: parent,
+		funcs:  map[Symbol]func(*Env, ReadOnlyTable) Value{},
+		macros: map[Symbol]func(*Env, ReadOnlyTable) Value{},
+		vars:   map[Symbol]Value{},
+		state:  state,
 	}
 }
 
-func newFuncEnv(parent *Env) Env {
-	return Env{
-		parent:    parent,
-		funcs:     map[Symbol]func(*Env, ReadOnlyTable) Value{},
-		macros:    map[Symbol]func(*Env, ReadOnlyTable) Value{},
-		vars:      map[Symbol]Value{},
-		isFuncEnv: true,
+// Stdout returns writer used as standard output by I/O builtins.
+func (e *Env) Stdout() io.Writer {
+	return e.state.stdout
+}
+
+// SetStdout sets writer used as standard output by I/O builtins. It is shared
+// by all environments derived from the same root. Defaults to os.Stdout.
+func (e *Env) SetStdout(w io.Writer) {
+	e.state.stdout = w
+}
+
+// Stderr returns writer used as standard error by I/O builtins.
+func (e *Env) Stderr() io.Writer {
+	return e.state.stderr
+}
+
+// SetStderr sets writer used as standard error by I/O builtins. It is shared
+// by all environments derived from the same root. Defaults to os.Stderr.
+func (e *Env) SetStderr(w io.Writer) {
+	e.state.stderr = w
+}
+
+// SetStdin sets reader used as standard input by I/O builtins. It is shared
+// by all environments derived from the same root. Defaults to os.Stdin.
+func (e *Env) SetStdin(r io.Reader) {
+	e.state.stdin = bufio.NewReader(r)
+}
+
+// position returns source position of the given expression.
+func (e *Env) position(expr Value) Position {
+	tab, isTable := expr.(*Table)
+	if !isTable {
+		return Position{}
 	}
+
+	return tab.pos
 }
 
-func (e *Env) globalEnv() *Env {
-	current := e
-	for {
-		if current == nil || current.isFuncEnv == false {
-			return current
-		}
+// stack returns a copy of current call stack, innermost call first.
+func (e *Env) stack() []StackFrame {
+	frames := e.state.frames
+	if len(frames) == 0 {
+		return nil
+	}
+
+	stack := make([]StackFrame, len(frames))
+	for i, frame := range frames {
+		stack[len(frames)-1-i] = frame
+	}
+
+	return stack
+}
+
+func (e *Env) pushFrame(frame StackFrame) {
+	e.state.frames = append(e.state.frames, frame)
+}
 
-		current = e.parent
+func (e *Env) popFrame() {
+	e.state.frames = e.state.frames[:len(e.state.frames)-1]
+}
+
+// evalError returns an EvalError of expr with its source position and current
+// call stack. EvalError causes are returned as is.
+func (e *Env) evalError(cause error, expr Value) EvalError {
+	if ee, isEvalErr := cause.(EvalError); isEvalErr {
+		return ee
+	}
+
+	return EvalError{
+		Cause:    cause,
+		Expr:     expr,
+		Position: e.position(expr),
+		Stack:    e.stack(),
 	}
 }
 
@@ -81,6 +221,18 @@ func (e *Env) getMacro(name Symbol) func(*Env, ReadOnlyTable) Value {
 	return nil
 }
 
+// getMacroExpander returns expander of the nearest macro with the given name.
+// Nil is returned if there is no such macro or if it isn't a DEFMACRO one.
+func (e *Env) getMacroExpander(name Symbol) func(ReadOnlyTable) Value {
+	for current := e; current != nil; current = current.parent {
+		if _, ok := current.macros[name]; ok {
+			return current.expanders[name]
+		}
+	}
+
+	return nil
+}
+
 func (e *Env) getVar(name Symbol) Value {
 	fn, ok := e.vars[name]
 	if ok {
@@ -102,6 +254,25 @@ func (e *Env) Defun(name Symbol, fn func(*Env, ReadOnlyTable) Value) {
 // Defmacro define a macro in the environment.
 func (e *Env) Defmacro(name Symbol, fn func(*Env, ReadOnlyTable) Value) {
 	e.macros[name] = fn
+	delete(e.expanders, name)
+}
+
+// defmacroExpander define a macro in the environment from the given expander.
+// Expansion of the macro form is evaluated within the caller environment.
+func (e *Env) defmacroExpander(name Symbol, expander func(ReadOnlyTable) Value) {
+	e.Defmacro(name, func(env *Env, tab ReadOnlyTable) Value {
+		expansion := expander(tab)
+		if _, isErr := expansion.(error); isErr {
+			return expansion
+		}
+
+		return tail(env, expansion)
+	})
+
+	if e.expanders == nil {
+		e.expanders = map[Symbol]func(ReadOnlyTable) Value{}
+	}
+	e.expanders[name] = expander
 }
 
 // Defvar define a variable in the environment.
@@ -109,51 +280,148 @@ func (e *Env) Defvar(name Symbol, v Value) {
 	e.vars[name] = v
 }
 
-// Eval evaluates the given value within the environment and returns a new value.
-func (e *Env) Eval(v Value) Value {
-	fn := func() Value {
-		if v == nil {
-			return v
+// Setvar updates value of the nearest variable with the given name in the
+// environment or its parents. False is returned if variable is not bound.
+func (e *Env) Setvar(name Symbol, v Value) bool {
+	for current := e; current != nil; current = current.parent {
+		if _, ok := current.vars[name]; ok {
+			current.vars[name] = v
+			return true
 		}
+	}
 
-		switch value := v.(type) {
-		case Symbol:
-			return e.getVar(value)
+	return false
+}
 
-		case *Table:
-			name := value.Get(0)
-			symbol, isSymbol := name.(Symbol)
-			if isSymbol {
-				macro := e.getMacro(symbol)
-				// Function.
-				if macro == nil {
-					return e.evalFunc(value, symbol)
-				}
+// tailCall is returned instead of a value by expressions evaluated in tail
+// position (last form of a body, IF branches, etc). It is evaluated by the
+// caller so tail calls don't grow the Go stack.
+type tailCall struct {
+	env  *Env
+	expr Value
+	// Frame of the function call that returned this tail call, if any.
+	frame *StackFrame
+}
 
-				// Macro.
-				return macro(e, value)
-			}
-			return EvalError{Cause: Error("function/macro name is not a symbol"), Expr: v}
+// tail returns a tail call of expr within env.
+func tail(env *Env, expr Value) Value {
+	return tailCall{env: env, expr: expr}
+}
+
+// trampoline evaluates tail calls until a value is produced. Frames of tail
+// calls replace each other on the call stack so it doesn't grow either.
+//
+// All evaluations happen within a trampoline, nested trampolines are counted
+// against maximum evaluation depth.
+func trampoline(v Value) Value {
+	tc, isTailCall := v.(tailCall)
+	if !isTailCall {
+		return v
+	}
+
+	env := tc.env
+	if err := env.enter(); err != nil {
+		return env.evalError(err, tc.expr)
+	}
+	defer env.leave()
+
+	var framed *Env
+	defer func() {
+		if framed != nil {
+			framed.popFrame()
+		}
+	}()
 
-		default:
+	for {
+		tc, isTailCall := v.(tailCall)
+		if !isTailCall {
 			return v
 		}
+
+		if tc.frame != nil {
+			if framed != nil {
+				framed.popFrame()
+			}
+			framed = tc.env
+			framed.pushFrame(*tc.frame)
+		}
+
+		v = tc.env.eval(tc.expr)
 	}
+}
 
-	res := fn()
-	return res
+// Eval evaluates the given value within the environment and returns a new value.
+func (e *Env) Eval(v Value) Value {
+	return trampoline(tail(e, v))
 }
 
-func (e *Env) evalFunc(tab ReadOnlyTable, fnName Symbol) Value {
-	fn := e.getFunc(fnName)
-	if fn == nil {
-		return EvalError{Cause: Error("function not found"), Expr: tab}
+// eval evaluates the given value within the environment. Unlike Eval, returned
+// value may be a tail call.
+func (e *Env) eval(v Value) Value {
+	if err := e.step(); err != nil {
+		return e.evalError(err, v)
+	}
+
+	if v == nil {
+		return v
 	}
 
+	switch value := v.(type) {
+	case Symbol:
+		return e.getVar(value)
+
+	case *Table:
+		name := value.Get(0)
+		switch head := name.(type) {
+		case Symbol:
+			// Macro.
+			if macro := e.getMacro(head); macro != nil {
+				result := macro(e, value)
+				if err, isErr := result.(error); isErr {
+					return e.evalError(err, v)
+				}
+				return result
+			}
+
+			// Function.
+			fn := e.resolveFunc(head)
+			if fn == nil {
+				return e.evalError(Error("function not found"), v)
+			}
+			return e.evalFunc(head, value, fn)
+
+		case *Table:
+			// Expression returning a function.
+			f := e.Eval(head)
+			if err, isErr := f.(error); isErr {
+				return e.evalError(err, v)
+			}
+
+			fn := e.resolveFunc(f)
+			if fn == nil {
+				return e.evalError(Error("value is not a function"), v)
+			}
+
+			name := Symbol("LAMBDA")
+			if function, isFunc := f.(*Function); isFunc && function.Name() != "" {
+				name = function.Name()
+			}
+			return e.evalFunc(name, value, fn)
+		}
+		return e.evalError(Error("function/macro name is not a symbol"), v)
+
+	default:
+		return v
+	}
+}
+
+// evalFunc evaluates arguments of a function call and calls fn. Call is
+// recorded on the call stack under the given name.
+func (e *Env) evalFunc(name Symbol, tab *Table, fn func(*Env, ReadOnlyTable) Value) Value {
 	var args Table
 	for k, v := range tab.Iter() {
-		// Copy function name symbol.
-		if v == fnName {
+		// Copy function name.
+		if k == 0 {
 			args.Set(k, v)
 			continue
 		}
@@ -161,15 +429,27 @@ func (e *Env) evalFunc(tab ReadOnlyTable, fnName Symbol) Value {
 		arg := e.Eval(v)
 		err, isErr := arg.(error)
 		if isErr {
-			return EvalError{Cause: err, Expr: tab}
+			return e.evalError(err, tab)
 		}
 
 		args.Set(k, arg)
 	}
 
-	result := fn(e.globalEnv(), &args)
-	if err, isErr := result.(error); isErr {
-		return EvalError{Cause: err, Expr: tab}
+	frame := StackFrame{Function: name, Position: e.position(tab)}
+	e.pushFrame(frame)
+	defer e.popFrame()
+
+	result := fn(e, &args)
+	switch value := result.(type) {
+	case error:
+		return e.evalError(value, tab)
+
+	case tailCall:
+		// Body of user defined functions is evaluated by the trampoline.
+		if value.frame == nil {
+			value.frame = &frame
+		}
+		return value
 	}
 
 	return result
@@ -0,0 +1,117 @@
+package tabp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnv(t *testing.T) {
+	t.Run("Vars", func(t *testing.T) {
+		t.Run("ParentLookup", func(t *testing.T) {
+			parent := NewEnv(nil)
+			child := NewEnv(&parent)
+
+			parent.Defvar("FOO", 1)
+			require.Equal(t, 1, child.getVar("FOO"))
+		})
+
+		t.Run("Shadowing", func(t *testing.T) {
+			parent := NewEnv(nil)
+			child := NewEnv(&parent)
+
+			parent.Defvar("FOO", 1)
+			child.Defvar("FOO", 2)
+			require.Equal(t, 1, parent.getVar("FOO"))
+			require.Equal(t, 2, child.getVar("FOO"))
+		})
+
+		t.Run("Setvar", func(t *testing.T) {
+			parent := NewEnv(nil)
+			child := NewEnv(&parent)
+
+			parent.Defvar("FOO", 1)
+			require.True(t, child.Setvar("FOO", 2))
+			require.Equal(t, 2, parent.getVar("FOO"))
+			require.False(t, child.Setvar("BAR", 2))
+		})
+	})
+
+	t.Run("Scope", func(t *testing.T) {
+		t.Run("Recursion", func(t *testing.T) {
+			result := evalTest(t, `
+				(defun sum-to (n)
+					(if (le n 1)
+						1
+						(add n (sum-to (sub n 1)))))
+				(sum-to 10)`)
+			require.Equal(t, 55, result)
+		})
+
+		t.Run("MutualRecursion", func(t *testing.T) {
+			result := evalTest(t, `
+				(defun even (n) (if (eq n 0) 'even (odd (sub n 1))))
+				(defun odd (n) (if (eq n 0) 'odd (even (sub n 1))))
+				(even 7)`)
+			require.Equal(t, Symbol("ODD"), result)
+		})
+
+		t.Run("NestedDefun", func(t *testing.T) {
+			result := evalTest(t, `
+				(defun outer (x)
+					(progn
+						(defun inner (y) (add x y))
+						(inner 2)))
+				(outer 40)`)
+			require.Equal(t, 42, result)
+		})
+
+		t.Run("NestedDefunIsLocal", func(t *testing.T) {
+			result := evalTest(t, `
+				(defun outer ()
+					(defun inner () 1))
+				(outer)
+				(inner)`)
+			require.IsType(t, EvalError{}, result)
+		})
+
+		t.Run("ParameterShadowsGlobal", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar x 1)
+				(defun f (x) x)
+				(add (f 2) x)`)
+			require.Equal(t, 3, result)
+		})
+
+		t.Run("CallerBindingsNotVisible", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar x 'global)
+				(defun get-x () x)
+				(defun caller (x) (get-x))
+				(caller 'local)`)
+			require.Equal(t, Symbol("GLOBAL"), result)
+		})
+
+		t.Run("DefinerBindingsVisible", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar x 'global)
+				(defun make-getter (x) (lambda () x))
+				(defun caller (x getter) (getter))
+				(caller 'caller (make-getter 'definer))`)
+			require.Equal(t, Symbol("DEFINER"), result)
+		})
+	})
+	t.Run("EvalContext", func(t *testing.T) {
+		t.Run("NilContext", func(t *testing.T) {
+			env := NewStdEnv()
+
+			var form Table
+			form.Append(Symbol("DOTIMES"))
+			form.Append(&Table{seq: []Value{Symbol("I"), 100000}})
+			form.Append(Symbol("I"))
+
+			result := env.EvalContext(nil, Limits{MaxSteps: 10}, &form)
+			require.ErrorIs(t, result.(error), ErrStepLimit)
+		})
+	})
+}
@@ -0,0 +1,50 @@
+package tabp
+
+import "errors"
+
+// ThrownError define errors raised by THROW.
+type ThrownError struct {
+	Data Value
+}
+
+// Error implements error.
+func (te ThrownError) Error() string {
+	if str, isString := te.Data.(string); isString {
+		return str
+	}
+
+	return Sexpr(te.Data)
+}
+
+// ToSExpr implements SExpr.
+func (te ThrownError) ToSExpr() string {
+	return te.Error()
+}
+
+// ErrorValue wraps an error caught by TRY so it can be manipulated as a regular
+// value instead of aborting evaluation.
+type ErrorValue struct {
+	Err error
+}
+
+// ToSExpr implements SExpr.
+func (ev *ErrorValue) ToSExpr() string {
+	return "#<ERROR " + errorMessage(ev.Err) + ">"
+}
+
+// errorMessage returns message of the root cause of err or thrown value
+// message if err was raised by THROW.
+func errorMessage(err error) string {
+	var thrown ThrownError
+	if errors.As(err, &thrown) {
+		return thrown.Error()
+	}
+
+	for {
+		cause := errors.Unwrap(err)
+		if cause == nil {
+			return err.Error()
+		}
+		err = cause
+	}
+}
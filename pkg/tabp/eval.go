@@ -2,61 +2,45 @@ package tabp
 
 import (
 	"bytes"
-	"errors"
 	"io"
 )
 
-type program struct {
-	parser Parser
-	env    Env
-}
-
 // Same as Eval but uses the given string as tabp program.
 func EvalString(tabp string) Value {
 	return Eval(bytes.NewBufferString(tabp))
 }
 
+// NewStdEnv returns a new root environment with all tabp standard library
+// modules installed.
+func NewStdEnv() Env {
+	return NewModulesEnv(StdModules...)
+}
+
+// NewSandboxEnv returns a new root environment with sandbox standard library
+// modules installed. Sandboxed programs can't perform any I/O.
+func NewSandboxEnv() Env {
+	return NewModulesEnv(SandboxModules...)
+}
+
+// NewModulesEnv returns a new root environment with the given modules
+// installed.
+func NewModulesEnv(modules ...Module) Env {
+	env := NewEnv(nil)
+	for _, m := range modules {
+		m.Install(&env)
+	}
+
+	return env
+}
+
 // Eval reads, evaluates and returns a tabp program from the given reader.
+// Program is evaluated within a new Runtime, use Runtime to keep state between
+// evaluations.
 func Eval(r io.Reader) Value {
-	p := program{
-		parser: NewParser(r),
-		env:    NewEnv(nil),
+	_, err := NewRuntime().EvalReader(r)
+	if err != nil {
+		return err
 	}
 
-	// Variables.
-	p.env.Defvar("TABP-VERSION", "0.1.0")
-
-	// Macros.
-	p.env.Defmacro("QUOTE", macroQuote)
-	p.env.Defmacro("QUASIQUOTE", macroQuasiQuote)
-	p.env.Defmacro("DEFUN", macroDefun)
-	p.env.Defmacro("DEFVAR", macroDefvar)
-	p.env.Defmacro("IF", macroIf)
-
-	// Functions.
-	p.env.Defun("PROGN", fnProgn)
-	p.env.Defun("EQ", fnEq)
-	p.env.Defun("LT", fnLt)
-	p.env.Defun("LE", fnLe)
-	p.env.Defun("GT", fnGt)
-	p.env.Defun("GE", fnGe)
-	p.env.Defun("PRINTF", fnPrintf)
-	p.env.Defun("SPRINTF", fnSprintf)
-	p.env.Defun("ADD", fnAdd)
-	p.env.Defun("SUB", fnSub)
-
-	for {
-		value, parseErr := p.parser.Parse()
-		if parseErr.Cause != nil {
-			if errors.Is(parseErr, io.EOF) {
-				return nil
-			}
-			return parseErr
-		}
-
-		result := p.env.Eval(value)
-		if err, isErr := result.(error); isErr {
-			return err
-		}
-	}
+	return nil
 }
@@ -0,0 +1,819 @@
+package tabp
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// evalTest evaluates the given program within a new standard environment and
+// returns value of the last expression.
+func evalTest(t *testing.T, program string) Value {
+	env := NewStdEnv()
+	parser := NewParser(bytes.NewBufferString(program))
+
+	var result Value
+	for {
+		v, err := parser.Parse()
+		if err.Cause == io.EOF {
+			return result
+		}
+		require.NoError(t, err.Cause)
+
+		result = env.Eval(v)
+		if _, isErr := result.(error); isErr {
+			return result
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	t.Run("Lambda", func(t *testing.T) {
+		t.Run("Call", func(t *testing.T) {
+			result := evalTest(t, `((lambda (a b) (sub a b)) 3 2)`)
+			require.Equal(t, 1, result)
+		})
+
+		t.Run("Variable", func(t *testing.T) {
+			result := evalTest(t, `
+				(defun call-with-21 (fn) (fn 21))
+				(call-with-21 (lambda (x) (add x x)))`)
+			require.Equal(t, 42, result)
+		})
+
+		t.Run("Closure", func(t *testing.T) {
+			result := evalTest(t, `
+				(defun adder (n) (lambda (x) (add x n)))
+				(add ((adder 2) 1) ((adder 3) 1))`)
+			require.Equal(t, 7, result)
+		})
+
+		t.Run("ImplicitProgn", func(t *testing.T) {
+			result := evalTest(t, `((lambda () 1 2 3))`)
+			require.Equal(t, 3, result)
+		})
+	})
+
+	t.Run("Funcall", func(t *testing.T) {
+		t.Run("Lambda", func(t *testing.T) {
+			result := evalTest(t, `(funcall (lambda (a b) (sub a b)) 3 2)`)
+			require.Equal(t, 1, result)
+		})
+
+		t.Run("Symbol", func(t *testing.T) {
+			result := evalTest(t, `(funcall 'add 1 2)`)
+			require.Equal(t, 3, result)
+		})
+
+		t.Run("NotAFunction", func(t *testing.T) {
+			result := evalTest(t, `(funcall 1 2)`)
+			require.IsType(t, EvalError{}, result)
+		})
+	})
+
+	t.Run("Apply", func(t *testing.T) {
+		result := evalTest(t, `(apply 'sub 10 '(3 2))`)
+		require.Equal(t, 5, result)
+	})
+
+	t.Run("Let", func(t *testing.T) {
+		t.Run("Sequence", func(t *testing.T) {
+			result := evalTest(t, `(let ((x 1) (y (add 1 1))) (add x y))`)
+			require.Equal(t, 3, result)
+		})
+
+		t.Run("Keyed", func(t *testing.T) {
+			result := evalTest(t, `(let (x: 1 y: 2) (sub y x))`)
+			require.Equal(t, 1, result)
+		})
+
+		t.Run("Shadowing", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar x 1)
+				(let ((x 2)) x)`)
+			require.Equal(t, 2, result)
+		})
+
+		t.Run("DoesNotLeak", func(t *testing.T) {
+			result := evalTest(t, `
+				(let ((x 2)) x)
+				x`)
+			require.Nil(t, result)
+		})
+
+		t.Run("ParallelBinding", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar x 1)
+				(let ((x 2) (y x)) y)`)
+			require.Equal(t, 1, result)
+		})
+
+		t.Run("SequentialBinding", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar x 1)
+				(let* ((x 2) (y x)) y)`)
+			require.Equal(t, 2, result)
+		})
+	})
+
+	t.Run("Defvar", func(t *testing.T) {
+		result := evalTest(t, `
+			(defvar x (add 1 2))
+			x`)
+		require.Equal(t, 3, result)
+	})
+
+	t.Run("Setq", func(t *testing.T) {
+		t.Run("Global", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar x 1)
+				(setq x (add x 1))
+				x`)
+			require.Equal(t, 2, result)
+		})
+
+		t.Run("FromFunction", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar counter 0)
+				(defun incr () (setq counter (add counter 1)))
+				(incr)
+				(incr)
+				counter`)
+			require.Equal(t, 2, result)
+		})
+
+		t.Run("NearestBinding", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar x 1)
+				(let ((x 2))
+					(set! x 3))
+				x`)
+			require.Equal(t, 1, result)
+		})
+
+		t.Run("Unbound", func(t *testing.T) {
+			result := evalTest(t, `(setq x 1)`)
+			require.IsType(t, EvalError{}, result)
+		})
+	})
+
+	t.Run("Constants", func(t *testing.T) {
+		for _, program := range []string{
+			`(setq nil 1)`,
+			`(setq t nil)`,
+			`(defvar false t)`,
+			`(let ((true nil)) true)`,
+			`(let* (nil: 1) nil)`,
+			`(defun f (t) t)`,
+			`(lambda (&rest nil) nil)`,
+			`(dotimes (t 3))`,
+			`(foreach (k nil (table 1 2)))`,
+			`(try (throw "err") (catch t t))`,
+		} {
+			t.Run(program, func(t *testing.T) {
+				result := evalTest(t, program)
+				require.IsType(t, EvalError{}, result)
+			})
+		}
+
+		result := evalTest(t, `(try (setq nil 1) (catch err nil))`)
+		require.Nil(t, result)
+	})
+
+	t.Run("TailCall", func(t *testing.T) {
+		// Deep non tail recursion would exceed this limit and crash.
+		defer debug.SetMaxStack(debug.SetMaxStack(1 << 20))
+
+		t.Run("If", func(t *testing.T) {
+			result := evalTest(t, `
+				(defun count-down (n)
+					(if (eq n 0)
+						'done
+						(count-down (sub n 1))))
+				(count-down 100000)`)
+			require.Equal(t, Symbol("DONE"), result)
+		})
+
+		t.Run("Progn", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar calls 0)
+				(defun count-down (n)
+					(progn
+						(setq calls (add calls 1))
+						(if (eq n 0) calls (count-down (sub n 1)))))
+				(count-down 100000)`)
+			require.Equal(t, 100001, result)
+		})
+
+		t.Run("MutualRecursion", func(t *testing.T) {
+			result := evalTest(t, `
+				(defun ping (n) (if (eq n 0) 'ping (pong (sub n 1))))
+				(defun pong (n) (if (eq n 0) 'pong (ping (sub n 1))))
+				(ping 100001)`)
+			require.Equal(t, Symbol("PONG"), result)
+		})
+
+		t.Run("Lambda", func(t *testing.T) {
+			result := evalTest(t, `
+				(defun loop (n fn) (if (eq n 0) (fn) (loop (sub n 1) fn)))
+				(loop 100000 (lambda () 'done))`)
+			require.Equal(t, Symbol("DONE"), result)
+		})
+	})
+
+	t.Run("Loop", func(t *testing.T) {
+		t.Run("While", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar i 0)
+				(defvar sum 0)
+				(while (lt i 5)
+					(setq sum (add sum i))
+					(setq i (add i 1)))
+				sum`)
+			require.Equal(t, 10, result)
+		})
+
+		t.Run("Dotimes", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar sum 0)
+				(dotimes (i 5)
+					(setq sum (add sum i)))
+				sum`)
+			require.Equal(t, 10, result)
+		})
+
+		t.Run("DotimesResult", func(t *testing.T) {
+			result := evalTest(t, `(dotimes (i 5 i))`)
+			require.Equal(t, 5, result)
+		})
+
+		t.Run("Foreach", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar sum 0)
+				(foreach (k v '(1 2 3 a: 4 b: 5))
+					(setq sum (add sum v)))
+				sum`)
+			require.Equal(t, 15, result)
+		})
+
+		t.Run("ForeachSeq", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar sum 0)
+				(foreach-seq (i v '(1 2 3 a: 4 b: 5))
+					(setq sum (add sum i v)))
+				sum`)
+			require.Equal(t, 9, result)
+		})
+
+		t.Run("ForeachKVs", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar sum 0)
+				(foreach-kvs (k v '(1 2 3 a: 4 b: 5))
+					(setq sum (add sum v)))
+				sum`)
+			require.Equal(t, 9, result)
+		})
+
+		t.Run("ForeachNonTable", func(t *testing.T) {
+			result := evalTest(t, `(foreach (k v 1) k)`)
+			require.ErrorIs(t, result.(error), Error("foreach can't iterate over a non table value"))
+		})
+
+		t.Run("Error", func(t *testing.T) {
+			result := evalTest(t, `(dotimes (i 5) (foo))`)
+			require.IsType(t, EvalError{}, result)
+		})
+	})
+
+	t.Run("Table", func(t *testing.T) {
+		t.Run("Constructor", func(t *testing.T) {
+			result := evalTest(t, `(table 1 (add 1 1) foo: 3)`)
+			require.IsType(t, &Table{}, result)
+			tab := result.(*Table)
+			require.Equal(t, []Value{1, 2}, tab.Seq())
+			require.Equal(t, 3, tab.Get(Symbol("FOO")))
+		})
+
+		t.Run("GetSet", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar tab (table))
+				(set tab 'foo 1)
+				(add (get tab 'foo) (get tab 'bar 2))`)
+			require.Equal(t, 3, result)
+		})
+
+		t.Run("Has", func(t *testing.T) {
+			result := evalTest(t, `(table (has '(a: 1) 'a) (has '(a: 1) 'b))`)
+			require.Equal(t, true, result.(*Table).Get(0))
+			require.Equal(t, 1, result.(*Table).Len())
+		})
+
+		t.Run("Cycle", func(t *testing.T) {
+			tcases := []struct {
+				program  string
+				expected Value
+			}{
+				{`(defvar x (table 1)) (append x x) (to-string x)`, "(1 #<CYCLE>)"},
+				{`(defvar x (table)) (set x 'self x) (to-string (table x))`, "((SELF: #<CYCLE>))"},
+				{`
+					(defvar a (table 1)) (append a a)
+					(defvar b (table 1)) (append b b)
+					(eq a b)`, true},
+				{`
+					(defvar a (table 1)) (append a a)
+					(defvar b (table 2)) (append b b)
+					(eq a b)`, nil},
+			}
+			for _, tcase := range tcases {
+				t.Run(tcase.program, func(t *testing.T) {
+					result := evalTest(t, tcase.program)
+					require.Equal(t, tcase.expected, result)
+				})
+			}
+
+			result := evalTest(t, `(defvar x (table)) (append x x) (throw x)`)
+			require.ErrorContains(t, result.(error), "(#<CYCLE>)")
+		})
+
+		t.Run("AppendInsertDelete", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar tab (table 1 2))
+				(append tab 4 5)
+				(insert tab 2 3)
+				(delete tab 4)
+				tab`)
+			require.Equal(t, "(1 2 3 4)", Sexpr(result))
+		})
+
+		t.Run("Len", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar tab '(a b c d: 1))
+				(table (len tab) (seqlen tab) (kvslen tab))`)
+			require.Equal(t, []Value{4, 3, 1}, result.(*Table).Seq())
+		})
+
+		t.Run("KeysValues", func(t *testing.T) {
+			result := evalTest(t, `
+				(defvar tab '(a b))
+				(table (keys tab) (values tab))`)
+			require.Equal(t, "((0 1) (A B))", Sexpr(result))
+		})
+
+		t.Run("NonTable", func(t *testing.T) {
+			result := evalTest(t, `(get 1 'foo)`)
+			require.IsType(t, EvalError{}, result)
+		})
+	})
+
+	t.Run("Math", func(t *testing.T) {
+		tcases := []struct {
+			program  string
+			expected Value
+		}{
+			{"(add 1 2 3)", 6},
+			{"(add 1.0 2)", 3.0},
+			{"(sub 0.0 1)", -1.0},
+			{"(sub 10 1 2)", 7},
+			{"(mul 2 3 4)", 24},
+			{"(mul 2 1.5)", 3.0},
+			{"(div 7 2)", 3},
+			{"(div 7 2.0)", 3.5},
+			{"(mod 7 3)", 1},
+			{"(mod -7 3)", -1},
+			{"(mod 7.5 2)", 1.5},
+			{"(pow 2 10)", 1024},
+			{"(pow 2 -1)", 0.5},
+			{"(pow 4 0.5)", 2.0},
+			{"(neg 3)", -3},
+			{"(neg 3.5)", -3.5},
+			{"(abs -3)", 3},
+			{"(abs -3.5)", 3.5},
+			{"(min 3 1 2)", 1},
+			{"(min 3 1.0 2)", 1.0},
+			{"(max 3 1 2)", 3},
+			{"(lt 1 1.5)", true},
+			{"(le 2 2)", true},
+			{"(gt 0.5 1)", nil},
+			{"(ge 2 1)", true},
+			{"(le 2 1)", nil},
+			{"(eq (lt 2 1) (eq 1 2))", true},
+			{"(nilp (gt 0 1))", true},
+		}
+		for _, tcase := range tcases {
+			t.Run(tcase.program, func(t *testing.T) {
+				result := evalTest(t, tcase.program)
+				require.Equal(t, tcase.expected, result)
+			})
+		}
+
+		t.Run("Errors", func(t *testing.T) {
+			for _, program := range []string{
+				"(div 1 0)",
+				"(div 1.0 0)",
+				"(mod 1 0)",
+				"(add 9223372036854775807 1)",
+				"(sub -9223372036854775807 2)",
+				"(mul 9223372036854775807 2)",
+				"(pow 2 64)",
+				`(add 1 "2")`,
+			} {
+				t.Run(program, func(t *testing.T) {
+					result := evalTest(t, program)
+					require.IsType(t, EvalError{}, result)
+				})
+			}
+		})
+
+		t.Run("Uint64", func(t *testing.T) {
+			_, err := toNumber(uint64(math.MaxUint64))
+			require.Error(t, err)
+
+			n, err := toNumber(uint64(math.MaxInt))
+			require.NoError(t, err)
+			require.Equal(t, math.MaxInt, n.int)
+		})
+	})
+
+	t.Run("Logic", func(t *testing.T) {
+		tcases := []struct {
+			program  string
+			expected Value
+		}{
+			{"t", true},
+			{"true", true},
+			{"false", false},
+			{"nil", nil},
+			{"(and)", true},
+			{"(and 1 2 3)", 3},
+			{"(and 1 nil 3)", nil},
+			{"(and 1 false 3)", false},
+			{"(or)", nil},
+			{"(or nil false 3)", 3},
+			{"(or nil false)", false},
+			{"(not nil)", true},
+			{"(not false)", true},
+			{"(not 0)", nil},
+			{"(if (not (eq 1 2)) 'yes 'no)", Symbol("YES")},
+			// Tables are compared by content, source position is ignored.
+			{"(eq '(1 (2) a: 3) '(1 (2) a: 3) (table 1 (table 2) a: 3))", true},
+			{"(eq '(1 2) '(1 3))", nil},
+			// Short circuit.
+			{"(and nil (undefined-function))", nil},
+			{"(or t (undefined-function))", true},
+		}
+		for _, tcase := range tcases {
+			t.Run(tcase.program, func(t *testing.T) {
+				result := evalTest(t, tcase.program)
+				require.Equal(t, tcase.expected, result)
+			})
+		}
+	})
+
+	t.Run("Conditional", func(t *testing.T) {
+		tcases := []struct {
+			program  string
+			expected Value
+		}{
+			{"(when t 1 2)", 2},
+			{"(when nil 1 2)", nil},
+			{"(when false 1 2)", nil},
+			{"(unless false 1 2)", 2},
+			{"(unless nil 1 2)", 2},
+			{"(unless t 1 2)", nil},
+			{"(cond ((eq 1 2) 'a) ((eq 1 1) 'b) (t 'c))", Symbol("B")},
+			{"(cond ((eq 1 2) 'a) (t 'c))", Symbol("C")},
+			{"(cond ((eq 1 2) 'a))", nil},
+			{"(cond ((add 1 2)))", 3},
+			{"(case (add 1 1) (1 'one) (2 'two) (t 'other))", Symbol("TWO")},
+			{"(case 'b ((a b c) 'abc) (otherwise 'other))", Symbol("ABC")},
+			{`(case "foo" ("bar" 'bar) ("foo" 'foo))`, Symbol("FOO")},
+			{"(case 3 (1 'one) (t 'other))", Symbol("OTHER")},
+			{"(case 3 (1 'one))", nil},
+			{"(case '(1 2) (((1 2)) 'match) (t 'other))", Symbol("MATCH")},
+		}
+		for _, tcase := range tcases {
+			t.Run(tcase.program, func(t *testing.T) {
+				result := evalTest(t, tcase.program)
+				require.Equal(t, tcase.expected, result)
+			})
+		}
+	})
+
+	t.Run("String", func(t *testing.T) {
+		tcases := []struct {
+			program  string
+			expected Value
+		}{
+			{`(concat "foo" "bar" "baz")`, "foobarbaz"},
+			{`(strlen "héllo")`, 5},
+			{`(substr "héllo" 1 3)`, "él"},
+			{`(substr "héllo" 2)`, "llo"},
+			{`(join (split "a,b,c" ",") "-")`, "a-b-c"},
+			{`(join '("a" "b"))`, "ab"},
+			{`(seqlen (split "a,b,c" ","))`, 3},
+			{`(upcase "foo")`, "FOO"},
+			{`(downcase "FOO")`, "foo"},
+			{`(trim "  foo ")`, "foo"},
+			{`(trim "--foo-" "-")`, "foo"},
+			{`(contains "foobar" "oba")`, true},
+			{`(contains "foobar" "baz")`, nil},
+			{`(index "héllo" "l")`, 2},
+			{`(index "hello" "z")`, nil},
+			{`(replace "aaa" "a" "b")`, "bbb"},
+			{`(replace "aaa" "a" "b" 2)`, "bba"},
+			{`(starts-with "foobar" "foo")`, true},
+			{`(ends-with "foobar" "foo")`, nil},
+			{`(string-to-symbol "foo")`, Symbol("FOO")},
+			{`(symbol-to-string 'foo)`, "FOO"},
+		}
+		for _, tcase := range tcases {
+			t.Run(tcase.program, func(t *testing.T) {
+				result := evalTest(t, tcase.program)
+				require.Equal(t, tcase.expected, result)
+			})
+		}
+
+		t.Run("Errors", func(t *testing.T) {
+			for _, program := range []string{
+				`(concat "foo" 1)`,
+				`(substr "foo" 2 10)`,
+				`(split 'foo ",")`,
+				`(symbol-to-string "foo")`,
+			} {
+				t.Run(program, func(t *testing.T) {
+					result := evalTest(t, program)
+					require.IsType(t, EvalError{}, result)
+				})
+			}
+		})
+	})
+
+	t.Run("Type", func(t *testing.T) {
+		tcases := []struct {
+			program  string
+			expected Value
+		}{
+			{`(type-of '(1 2))`, Symbol("TABLE")},
+			{`(type-of 'foo)`, Symbol("SYMBOL")},
+			{`(type-of "foo")`, Symbol("STRING")},
+			{`(type-of 1)`, Symbol("INTEGER")},
+			{`(type-of 1.5)`, Symbol("FLOAT")},
+			{`(type-of (lambda () 1))`, Symbol("FUNCTION")},
+			{`(type-of nil)`, Symbol("NIL")},
+			{`(type-of t)`, Symbol("BOOLEAN")},
+			{`(tablep '(1))`, true},
+			{`(tablep 1)`, nil},
+			{`(symbolp 'foo)`, true},
+			{`(stringp "foo")`, true},
+			{`(numberp 1.5)`, true},
+			{`(numberp "1")`, nil},
+			{`(integerp 1)`, true},
+			{`(integerp 1.0)`, nil},
+			{`(floatp 1.0)`, true},
+			{`(functionp (lambda () 1))`, true},
+			{`(nilp nil)`, true},
+			{`(nilp false)`, nil},
+			{`(to-int 3.9)`, 3},
+			{`(to-int "42")`, 42},
+			{`(to-float 3)`, 3.0},
+			{`(to-float "1.5")`, 1.5},
+			{`(to-string "foo")`, "foo"},
+			{`(to-string 'foo)`, "FOO"},
+			{`(to-string '(1 "a"))`, `(1 "a")`},
+			{`(parse-number "12")`, 12},
+			{`(parse-number "1.5")`, 1.5},
+		}
+		for _, tcase := range tcases {
+			t.Run(tcase.program, func(t *testing.T) {
+				result := evalTest(t, tcase.program)
+				require.Equal(t, tcase.expected, result)
+			})
+		}
+
+		t.Run("Errors", func(t *testing.T) {
+			for _, program := range []string{
+				`(to-int "foo")`,
+				`(to-int 'foo)`,
+				`(to-float "foo")`,
+				`(parse-number "12abc")`,
+				`(parse-number 12)`,
+			} {
+				t.Run(program, func(t *testing.T) {
+					result := evalTest(t, program)
+					require.IsType(t, EvalError{}, result)
+				})
+			}
+		})
+	})
+
+	t.Run("Defmacro", func(t *testing.T) {
+		t.Run("UnevaluatedArgs", func(t *testing.T) {
+			result := evalTest(t, `
+				(defmacro my-unless (cond body)
+					(table 'if cond 'nil body))
+				(table (my-unless nil 1) (my-unless t (undefined-function)))`)
+			require.Equal(t, []Value{1}, result.(*Table).Seq())
+		})
+
+		t.Run("CallerEnv", func(t *testing.T) {
+			result := evalTest(t, `
+				(defmacro incr (name)
+					(table 'setq name (table 'add name 1)))
+				(defun f (x) (progn (incr x) x))
+				(f 41)`)
+			require.Equal(t, 42, result)
+		})
+
+		t.Run("Quasiquote", func(t *testing.T) {
+			result := evalTest(t, "(defmacro swap-sub (a b) `(sub ,b ,a)) (swap-sub 1 3)")
+			require.Equal(t, 2, result)
+		})
+
+		t.Run("MacroExpand1", func(t *testing.T) {
+			result := evalTest(t, `
+				(defmacro my-unless (cond body)
+					(table 'if cond 'nil body))
+				(macroexpand-1 '(my-unless c b))`)
+			require.Equal(t, "(IF C NIL B)", Sexpr(result))
+		})
+
+		t.Run("MacroExpand", func(t *testing.T) {
+			result := evalTest(t, `
+				(defmacro my-unless (cond body)
+					(table 'if cond 'nil body))
+				(defmacro my-when (cond body)
+					(table 'my-unless (table 'not cond) body))
+				(table
+					(macroexpand-1 '(my-when c b))
+					(macroexpand '(my-when c b)))`)
+			require.Equal(t, "((MY-UNLESS (NOT C) B) (IF (NOT C) NIL B))", Sexpr(result))
+		})
+
+		t.Run("MacroExpandNonMacro", func(t *testing.T) {
+			result := evalTest(t, `(macroexpand '(if a b c))`)
+			require.Equal(t, "(IF A B C)", Sexpr(result))
+		})
+	})
+
+	t.Run("Quasiquote", func(t *testing.T) {
+		tcases := []struct {
+			program  string
+			expected string
+		}{
+			{"`(a b)", "(A B)"},
+			{"`(a ,(add 1 2))", "(A 3)"},
+			{"`(a ,@(table 1 2) b)", "(A 1 2 B)"},
+			{"`(a ,@(table) b)", "(A B)"},
+			{"`(a ,@'(1 k: 2))", "(A 1 K: 2)"},
+			{"`(a (b ,@'(1 2)))", "(A (B 1 2))"},
+		}
+		for _, tcase := range tcases {
+			t.Run(tcase.program, func(t *testing.T) {
+				result := evalTest(t, tcase.program)
+				require.Equal(t, tcase.expected, Sexpr(result))
+			})
+		}
+
+		t.Run("SpliceNonTable", func(t *testing.T) {
+			result := evalTest(t, "`(a ,@1)")
+			require.ErrorIs(t, result.(error), Error("unquote-splicing value isn't a table"))
+		})
+
+		t.Run("FunctionCalledMultipleTimes", func(t *testing.T) {
+			result := evalTest(t, "(defun f (x y) `(x ,x y ,@y)) (table (f 1 '(a)) (f 2 '(b c)) (f 3 '()))")
+			require.Equal(t, "((X 1 Y A) (X 2 Y B C) (X 3 Y))", Sexpr(result))
+		})
+
+		t.Run("MacroExpandedMultipleTimes", func(t *testing.T) {
+			result := evalTest(t, "(defmacro swap-sub (a b) `(sub ,b ,a)) (table (swap-sub 1 3) (swap-sub 1 10))")
+			require.Equal(t, "(2 9)", Sexpr(result))
+		})
+
+		t.Run("TemplateUnchanged", func(t *testing.T) {
+			env := NewStdEnv()
+			parser := NewParser(bytes.NewBufferString("`(a ,x ,@y)"))
+			template, err := parser.Parse()
+			require.NoError(t, err.Cause)
+
+			env.Defvar("X", 1)
+			env.Defvar("Y", &Table{seq: []Value{2, 3}})
+			require.Equal(t, "(A 1 2 3)", Sexpr(env.Eval(template)))
+
+			env.Defvar("X", 4)
+			env.Defvar("Y", &Table{seq: []Value{5}})
+			require.Equal(t, "(A 4 5)", Sexpr(env.Eval(template)))
+
+			require.Equal(t, "(QUASIQUOTE (A (UNQUOTE X) (UNQUOTE-SPLICING Y)))", Sexpr(template))
+		})
+
+		t.Run("VariadicMacro", func(t *testing.T) {
+			result := evalTest(t, "(defmacro my-add (&rest args) `(add ,@args)) (my-add 1 2 3)")
+			require.Equal(t, 6, result)
+		})
+	})
+
+	t.Run("RestParam", func(t *testing.T) {
+		result := evalTest(t, `
+			(defun f (a &rest rest) (table a rest))
+			(f 1 2 3)`)
+		require.Equal(t, "(1 (2 3))", Sexpr(result))
+	})
+
+	t.Run("Try", func(t *testing.T) {
+		tcases := []struct {
+			program  string
+			expected Value
+		}{
+			{`(try 1 2)`, 2},
+			{`(try (throw "oops") (catch err (error-message err)))`, "oops"},
+			{`(try (throw '(code: 42)) (catch err (get (error-data err) 'code)))`, 42},
+			{`(try (undefined-function) (catch err (error-message err)))`, "function not found"},
+			{`(try (undefined-function) (catch err (error-data err)))`, nil},
+			{`(try (div 1 0) (catch err (type-of err)))`, Symbol("ERROR")},
+			{`(try 1 (catch err 2))`, 1},
+			{`
+				(defvar cleaned nil)
+				(try (throw 1) (catch err 2) (finally (setq cleaned t)))
+				cleaned`, true},
+			{`
+				(defvar cleaned nil)
+				(try 1 (finally (setq cleaned t)))
+				cleaned`, true},
+			{`
+				(defun check (n) (if (lt n 0) (throw "negative") n))
+				(defun safe-check (n) (try (check n) (catch err 0)))
+				(add (safe-check 1) (safe-check -1))`, 1},
+			{`
+				(try
+					(try (throw "inner") (catch err (throw err)))
+					(catch err (concat "outer: " (error-message err))))`, "outer: inner"},
+		}
+		for _, tcase := range tcases {
+			t.Run(tcase.program, func(t *testing.T) {
+				result := evalTest(t, tcase.program)
+				require.Equal(t, tcase.expected, result)
+			})
+		}
+
+		t.Run("Uncaught", func(t *testing.T) {
+			result := evalTest(t, `(try (throw "oops") (finally 1))`)
+			require.ErrorIs(t, result.(error), ThrownError{Data: "oops"})
+		})
+
+		t.Run("InvalidCatch", func(t *testing.T) {
+			rt := NewRuntime()
+			_, err := rt.EvalString(`
+				(defvar evaluated nil)
+				(try (setq evaluated t) (catch 1 2) (finally 3))`)
+			require.ErrorIs(t, err, Error("catch error variable name must be a symbol"))
+
+			// Body isn't evaluated.
+			evaluated, err := rt.EvalString(`evaluated`)
+			require.NoError(t, err)
+			require.Nil(t, evaluated)
+		})
+
+		t.Run("FinallyError", func(t *testing.T) {
+			result := evalTest(t, `(try 1 (finally (throw "cleanup")))`)
+			require.ErrorIs(t, result.(error), ThrownError{Data: "cleanup"})
+		})
+	})
+	t.Run("StackTrace", func(t *testing.T) {
+		result := evalTest(t, `(defun inner (n) (div n 0))
+(defun outer (n)
+  (add 1 (inner n)))
+(outer 1)`)
+
+		var evalErr EvalError
+		require.ErrorAs(t, result.(error), &evalErr)
+		require.ErrorIs(t, evalErr, Error("division by zero"))
+		require.Equal(t, 1, evalErr.Position.line)
+		require.Equal(t, []Symbol{"DIV", "INNER", "OUTER"}, stackFunctions(evalErr.Stack))
+		require.Equal(t, 3, evalErr.Stack[1].Position.line)
+		require.Equal(t, 4, evalErr.Stack[2].Position.line)
+		require.Contains(t, evalErr.Traceback(), "Traceback (most recent call first):\n  DIV called at 1:")
+	})
+
+	t.Run("TailCallStack", func(t *testing.T) {
+		result := evalTest(t, `
+			(defun loop (n) (if (eq n 0) (div 1 0) (loop (sub n 1))))
+			(loop 100)`)
+
+		var evalErr EvalError
+		require.ErrorAs(t, result.(error), &evalErr)
+		require.Equal(t, []Symbol{"DIV", "LOOP"}, stackFunctions(evalErr.Stack))
+	})
+}
+
+func stackFunctions(stack []StackFrame) []Symbol {
+	var names []Symbol
+	for _, frame := range stack {
+		names = append(names, frame.Function)
+	}
+
+	return names
+}
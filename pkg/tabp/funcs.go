@@ -1,70 +1,11 @@
 package tabp
 
-import (
-	"fmt"
-	"reflect"
-)
-
-func fnAdd(_ *Env, tab ReadOnlyTable) Value {
-	args := unsafeAnySlice(tab.Seq()[1:])
-	if len(args) < 1 {
-		return Error("no argument provided")
-	}
-
-	sum := 0
-	sumF := 0.0
-	for _, v := range args {
-		vInt, vFloat, ok := toNumber(v)
-		if !ok {
-			return Error("can't add non number type")
-		}
-
-		sum += vInt
-		sumF += vFloat
-	}
-
-	if sumF != 0.0 {
-		return sumF + float64(sum)
-	}
-
-	return sum
-}
-
-func fnSub(_ *Env, tab ReadOnlyTable) Value {
-	args := unsafeAnySlice(tab.Seq()[1:])
-	if len(args) < 1 {
-		return Error("no argument provided")
-	}
-
-	base, baseF, ok := toNumber(args[0])
-	if !ok {
-		return Error("can't substract non number type")
-	}
-
-	result := 0
-	resultF := 0.0
-
-	for _, v := range args[1:] {
-		vInt, vFloat, ok := toNumber(v)
-		if !ok {
-			return Error("can't substract non number type")
-		}
-
-		result -= vInt
-		resultF -= vFloat
-	}
-
-	if baseF != 0.0 || resultF != 0.0 {
-		return (float64(base) + baseF) + (resultF - float64(result))
-	}
-
-	return base + result
-}
+import "fmt"
 
 func fnEq(_ *Env, tab ReadOnlyTable) Value {
 	first := tab.Get(1)
 	for i := 2; i < tab.SeqLen(); i++ {
-		if !reflect.DeepEqual(first, tab.Get(i)) {
+		if !ValuesEqual(first, tab.Get(i)) {
 			return nil
 		}
 	}
@@ -72,115 +13,114 @@ func fnEq(_ *Env, tab ReadOnlyTable) Value {
 	return true
 }
 
-func fnLt(_ *Env, tab ReadOnlyTable) Value {
-	first := tab.Get(1)
-	second := tab.Get(2)
+func fnNot(_ *Env, tab ReadOnlyTable) Value {
+	return boolValue(!isTruthy(tab.Get(1)))
+}
 
-	a, aF, ok := toNumber(first)
-	if !ok {
-		return Error("can't compare non number type")
+// macroExpand1 expands form once if it is a DEFMACRO macro call. Form is
+// returned as is otherwise.
+func macroExpand1(env *Env, form Value) (Value, bool) {
+	tab, isTable := form.(*Table)
+	if !isTable || tab == nil {
+		return form, false
 	}
 
-	b, bF, ok := toNumber(second)
-	if !ok {
-		return Error("can't compare non number type")
+	name, isSymbol := tab.Get(0).(Symbol)
+	if !isSymbol {
+		return form, false
 	}
 
-	if aF != 0 || bF != 0 {
-		return aF+float64(a) < bF+float64(b)
+	expander := env.getMacroExpander(name)
+	if expander == nil {
+		return form, false
 	}
 
-	return a < b
+	return expander(tab), true
 }
 
-func fnLe(_ *Env, tab ReadOnlyTable) Value {
-	first := tab.Get(1)
-	second := tab.Get(2)
+func fnMacroExpand1(env *Env, tab ReadOnlyTable) Value {
+	expansion, _ := macroExpand1(env, tab.Get(1))
+	return expansion
+}
 
-	a, aF, ok := toNumber(first)
-	if !ok {
-		return Error("can't compare non number type")
-	}
+func fnMacroExpand(env *Env, tab ReadOnlyTable) Value {
+	form := tab.Get(1)
+	for {
+		expansion, expanded := macroExpand1(env, form)
+		if _, isErr := expansion.(error); isErr || !expanded {
+			return expansion
+		}
 
-	b, bF, ok := toNumber(second)
-	if !ok {
-		return Error("can't compare non number type")
+		form = expansion
 	}
+}
 
-	if aF != 0 || bF != 0 {
-		return aF+float64(a) <= bF+float64(b)
+func fnSprintf(env *Env, tab ReadOnlyTable) Value {
+	format, isString := tab.Get(1).(string)
+	if !isString {
+		return Error("format is not a string")
 	}
 
-	return a <= b
+	args := unsafeAnySlice(tab.Seq()[2:])
+	return env.allocString(fmt.Sprintf(string(format), args...))
 }
 
-func fnGt(_ *Env, tab ReadOnlyTable) Value {
-	first := tab.Get(1)
-	second := tab.Get(2)
-
-	a, aF, ok := toNumber(first)
-	if !ok {
-		return Error("can't compare non number type")
+func fnFuncall(env *Env, tab ReadOnlyTable) Value {
+	fn := env.resolveFunc(tab.Get(1))
+	if fn == nil {
+		return Error("funcall first argument is not a function")
 	}
 
-	b, bF, ok := toNumber(second)
-	if !ok {
-		return Error("can't compare non number type")
-	}
+	var args Table
+	for k, v := range tab.Iter() {
+		if i, isInt := k.(int); isInt && i >= 0 && i < tab.SeqLen() {
+			// Skip FUNCALL symbol.
+			if i > 0 {
+				args.Set(i-1, v)
+			}
+			continue
+		}
 
-	if aF != 0 || bF != 0 {
-		return aF+float64(a) > bF+float64(b)
+		args.Set(k, v)
+	}
+	if err := env.allocTable(&args, 0); err != nil {
+		return err
 	}
 
-	return a > b
+	return fn(env, &args)
 }
 
-func fnGe(_ *Env, tab ReadOnlyTable) Value {
-	first := tab.Get(1)
-	second := tab.Get(2)
-
-	a, aF, ok := toNumber(first)
-	if !ok {
-		return Error("can't compare non number type")
+func fnApply(env *Env, tab ReadOnlyTable) Value {
+	fn := env.resolveFunc(tab.Get(1))
+	if fn == nil {
+		return Error("apply first argument is not a function")
 	}
-
-	b, bF, ok := toNumber(second)
-	if !ok {
-		return Error("can't compare non number type")
+	if tab.SeqLen() < 3 {
+		return Error("apply arguments list is missing")
 	}
 
-	if aF != 0 || bF != 0 {
-		return aF+float64(a) >= bF+float64(b)
+	var args Table
+	// Function and arguments preceding arguments list.
+	for _, v := range tab.Seq()[1 : tab.SeqLen()-1] {
+		args.Append(v)
 	}
 
-	return a >= b
-}
-
-func fnPrintf(_ *Env, tab ReadOnlyTable) Value {
-	format, isString := tab.Get(1).(string)
-	if !isString {
-		return Error("format is not a string")
-	}
+	switch argsList := tab.Get(tab.SeqLen() - 1).(type) {
+	case nil:
+	case ReadOnlyTable:
+		for _, v := range argsList.Seq() {
+			args.Append(v)
+		}
+		for k, v := range argsList.IterKVs() {
+			args.Set(k, v)
+		}
 
-	args := unsafeAnySlice(tab.Seq()[2:])
-	for i, v := range args {
-		args[i] = Sexpr(v)
+	default:
+		return Error("apply last argument is not a table")
 	}
-	fmt.Printf(format, args...)
-
-	return nil
-}
-
-func fnSprintf(_ *Env, tab ReadOnlyTable) Value {
-	format, isString := tab.Get(1).(string)
-	if !isString {
-		return Error("format is not a string")
+	if err := env.allocTable(&args, 0); err != nil {
+		return err
 	}
 
-	args := unsafeAnySlice(tab.Seq()[2:])
-	return fmt.Sprintf(string(format), args...)
-}
-
-func fnProgn(_ *Env, tab ReadOnlyTable) Value {
-	return tab.Get(tab.SeqLen() - 1)
+	return fn(env, &args)
 }
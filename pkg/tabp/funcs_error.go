@@ -0,0 +1,38 @@
+package tabp
+
+import "errors"
+
+// fnThrow raises an error with its argument as payload. Caught errors are
+// raised again as is.
+func fnThrow(_ *Env, tab ReadOnlyTable) Value {
+	if ev, isErrorValue := tab.Get(1).(*ErrorValue); isErrorValue {
+		return ev.Err
+	}
+
+	return ThrownError{Data: tab.Get(1)}
+}
+
+func fnErrorMessage(_ *Env, tab ReadOnlyTable) Value {
+	ev, isErrorValue := tab.Get(1).(*ErrorValue)
+	if !isErrorValue {
+		return Error("argument is not an error")
+	}
+
+	return errorMessage(ev.Err)
+}
+
+// fnErrorData returns value thrown using THROW or nil if error wasn't raised
+// by THROW.
+func fnErrorData(_ *Env, tab ReadOnlyTable) Value {
+	ev, isErrorValue := tab.Get(1).(*ErrorValue)
+	if !isErrorValue {
+		return Error("argument is not an error")
+	}
+
+	var thrown ThrownError
+	if errors.As(ev.Err, &thrown) {
+		return thrown.Data
+	}
+
+	return nil
+}
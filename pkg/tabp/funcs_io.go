@@ -0,0 +1,80 @@
+package tabp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// fprintf writes formatted arguments of a function call to w. Arguments are
+// formatted as S-Expressions.
+func fprintf(w io.Writer, tab ReadOnlyTable) Value {
+	format, isString := tab.Get(1).(string)
+	if !isString {
+		return Error("format is not a string")
+	}
+
+	args := unsafeAnySlice(tab.Seq()[2:])
+	for i, v := range args {
+		args[i] = Sexpr(v)
+	}
+
+	_, err := fmt.Fprintf(w, format, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func fnPrintf(env *Env, tab ReadOnlyTable) Value {
+	return fprintf(env.Stdout(), tab)
+}
+
+func fnEprintf(env *Env, tab ReadOnlyTable) Value {
+	return fprintf(env.Stderr(), tab)
+}
+
+// fnPrint writes its arguments separated by a space to standard output.
+// Strings are written as is, other values as S-Expressions.
+func fnPrint(env *Env, tab ReadOnlyTable) Value {
+	return printValues(env.Stdout(), tab, "")
+}
+
+// fnPrintln is the same as fnPrint but terminates output with a newline.
+func fnPrintln(env *Env, tab ReadOnlyTable) Value {
+	return printValues(env.Stdout(), tab, "\n")
+}
+
+func printValues(w io.Writer, tab ReadOnlyTable, end string) Value {
+	parts := make([]string, 0, tab.SeqLen())
+	for _, v := range tab.Seq()[1:] {
+		parts = append(parts, toString(v))
+	}
+
+	_, err := io.WriteString(w, strings.Join(parts, " ")+end)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// fnReadLine reads a line from standard input and returns it without its
+// trailing newline. Nil is returned at end of input.
+func fnReadLine(env *Env, _ ReadOnlyTable) Value {
+	line, err := env.state.stdin.ReadString('\n')
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+		// Last line may not be terminated by a newline.
+		if line == "" {
+			return nil
+		}
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r")
+}
@@ -0,0 +1,342 @@
+package tabp
+
+import "math"
+
+// numberArgs converts arguments of a function call to numbers. If any of them
+// is a float, all of them are promoted to float. Hence, arithmetic functions
+// returns a float if any operand is a float and an integer otherwise.
+func numberArgs(tab ReadOnlyTable) ([]number, bool, error) {
+	args := tab.Seq()[1:]
+	if len(args) < 1 {
+		return nil, false, Error("no argument provided")
+	}
+
+	numbers := make([]number, len(args))
+	isFloat := false
+	for i, v := range args {
+		n, err := toNumber(v)
+		if err != nil {
+			return nil, false, err
+		}
+
+		numbers[i] = n
+		isFloat = isFloat || n.isFloat
+	}
+
+	if isFloat {
+		for i, n := range numbers {
+			numbers[i] = number{float: n.toFloat(), isFloat: true}
+		}
+	}
+
+	return numbers, isFloat, nil
+}
+
+// foldNumbers folds arguments of a function call using intOp if all arguments
+// are integers and floatOp otherwise.
+func foldNumbers(
+	tab ReadOnlyTable,
+	intOp func(a, b int) (int, error),
+	floatOp func(a, b float64) (float64, error),
+) Value {
+	numbers, isFloat, err := numberArgs(tab)
+	if err != nil {
+		return err
+	}
+
+	if isFloat {
+		result := numbers[0].float
+		for _, n := range numbers[1:] {
+			result, err = floatOp(result, n.float)
+			if err != nil {
+				return err
+			}
+		}
+
+		return result
+	}
+
+	result := numbers[0].int
+	for _, n := range numbers[1:] {
+		result, err = intOp(result, n.int)
+		if err != nil {
+			return err
+		}
+	}
+
+	return result
+}
+
+func addInt(a, b int) (int, error) {
+	r := a + b
+	if (a^r)&(b^r) < 0 {
+		return 0, Error("integer overflow")
+	}
+
+	return r, nil
+}
+
+func subInt(a, b int) (int, error) {
+	r := a - b
+	if (a^b)&(a^r) < 0 {
+		return 0, Error("integer overflow")
+	}
+
+	return r, nil
+}
+
+func mulInt(a, b int) (int, error) {
+	if a == 0 || b == 0 {
+		return 0, nil
+	}
+
+	r := a * b
+	if r/b != a || (a == -1 && b == math.MinInt) || (b == -1 && a == math.MinInt) {
+		return 0, Error("integer overflow")
+	}
+
+	return r, nil
+}
+
+func divInt(a, b int) (int, error) {
+	if b == 0 {
+		return 0, Error("division by zero")
+	}
+	if a == math.MinInt && b == -1 {
+		return 0, Error("integer overflow")
+	}
+
+	return a / b, nil
+}
+
+func modInt(a, b int) (int, error) {
+	if b == 0 {
+		return 0, Error("division by zero")
+	}
+	if b == -1 {
+		return 0, nil
+	}
+
+	return a % b, nil
+}
+
+func powInt(base, exp int) (int, error) {
+	result := 1
+	for exp > 0 {
+		var err error
+		if exp&1 == 1 {
+			result, err = mulInt(result, base)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		exp >>= 1
+		if exp > 0 {
+			base, err = mulInt(base, base)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func addFloat(a, b float64) (float64, error) {
+	return a + b, nil
+}
+
+func subFloat(a, b float64) (float64, error) {
+	return a - b, nil
+}
+
+func mulFloat(a, b float64) (float64, error) {
+	return a * b, nil
+}
+
+func divFloat(a, b float64) (float64, error) {
+	if b == 0 {
+		return 0, Error("division by zero")
+	}
+
+	return a / b, nil
+}
+
+func modFloat(a, b float64) (float64, error) {
+	if b == 0 {
+		return 0, Error("division by zero")
+	}
+
+	return math.Mod(a, b), nil
+}
+
+func fnAdd(_ *Env, tab ReadOnlyTable) Value {
+	return foldNumbers(tab, addInt, addFloat)
+}
+
+func fnSub(_ *Env, tab ReadOnlyTable) Value {
+	return foldNumbers(tab, subInt, subFloat)
+}
+
+func fnMul(_ *Env, tab ReadOnlyTable) Value {
+	return foldNumbers(tab, mulInt, mulFloat)
+}
+
+// fnDiv performs a truncated division if all operands are integers.
+func fnDiv(_ *Env, tab ReadOnlyTable) Value {
+	return foldNumbers(tab, divInt, divFloat)
+}
+
+// fnMod returns remainder of a truncated division, result has the sign of the
+// dividend.
+func fnMod(_ *Env, tab ReadOnlyTable) Value {
+	return foldNumbers(tab, modInt, modFloat)
+}
+
+// fnPow returns base raised to the power of exp. Result is a float if exp is a
+// negative integer.
+func fnPow(_ *Env, tab ReadOnlyTable) Value {
+	if tab.SeqLen() != 3 {
+		return Error("pow expects a base and an exponent")
+	}
+
+	numbers, isFloat, err := numberArgs(tab)
+	if err != nil {
+		return err
+	}
+
+	base, exp := numbers[0], numbers[1]
+	if isFloat || exp.int < 0 {
+		return math.Pow(base.toFloat(), exp.toFloat())
+	}
+
+	result, err := powInt(base.int, exp.int)
+	if err != nil {
+		return err
+	}
+
+	return result
+}
+
+func fnNeg(_ *Env, tab ReadOnlyTable) Value {
+	if tab.SeqLen() != 2 {
+		return Error("neg expects a single argument")
+	}
+
+	n, err := toNumber(tab.Get(1))
+	if err != nil {
+		return err
+	}
+
+	if n.isFloat {
+		return -n.float
+	}
+
+	result, err := subInt(0, n.int)
+	if err != nil {
+		return err
+	}
+
+	return result
+}
+
+func fnAbs(_ *Env, tab ReadOnlyTable) Value {
+	if tab.SeqLen() != 2 {
+		return Error("abs expects a single argument")
+	}
+
+	n, err := toNumber(tab.Get(1))
+	if err != nil {
+		return err
+	}
+
+	if n.isFloat {
+		return math.Abs(n.float)
+	}
+
+	if n.int < 0 {
+		result, err := subInt(0, n.int)
+		if err != nil {
+			return err
+		}
+
+		return result
+	}
+
+	return n.int
+}
+
+func fnMin(_ *Env, tab ReadOnlyTable) Value {
+	return foldNumbers(tab,
+		func(a, b int) (int, error) { return min(a, b), nil },
+		func(a, b float64) (float64, error) { return math.Min(a, b), nil },
+	)
+}
+
+func fnMax(_ *Env, tab ReadOnlyTable) Value {
+	return foldNumbers(tab,
+		func(a, b int) (int, error) { return max(a, b), nil },
+		func(a, b float64) (float64, error) { return math.Max(a, b), nil },
+	)
+}
+
+// compareNumbers compares the two arguments of a function call and returns -1,
+// 0 or +1 if first argument is respectively less than, equal or greater than
+// second argument.
+func compareNumbers(tab ReadOnlyTable) (int, error) {
+	if tab.SeqLen() != 3 {
+		return 0, Error("comparison expects two arguments")
+	}
+
+	numbers, isFloat, err := numberArgs(tab)
+	if err != nil {
+		return 0, err
+	}
+
+	a, b := numbers[0], numbers[1]
+	switch {
+	case isFloat && a.float < b.float, !isFloat && a.int < b.int:
+		return -1, nil
+	case isFloat && a.float > b.float, !isFloat && a.int > b.int:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func fnLt(_ *Env, tab ReadOnlyTable) Value {
+	cmp, err := compareNumbers(tab)
+	if err != nil {
+		return err
+	}
+
+	return boolValue(cmp < 0)
+}
+
+func fnLe(_ *Env, tab ReadOnlyTable) Value {
+	cmp, err := compareNumbers(tab)
+	if err != nil {
+		return err
+	}
+
+	return boolValue(cmp <= 0)
+}
+
+func fnGt(_ *Env, tab ReadOnlyTable) Value {
+	cmp, err := compareNumbers(tab)
+	if err != nil {
+		return err
+	}
+
+	return boolValue(cmp > 0)
+}
+
+func fnGe(_ *Env, tab ReadOnlyTable) Value {
+	cmp, err := compareNumbers(tab)
+	if err != nil {
+		return err
+	}
+
+	return boolValue(cmp >= 0)
+}
@@ -0,0 +1,18 @@
+package tabp
+
+import "os"
+
+// fnGetenv returns value of an environment variable or nil if it isn't set.
+func fnGetenv(_ *Env, tab ReadOnlyTable) Value {
+	name, err := stringArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return nil
+	}
+
+	return value
+}
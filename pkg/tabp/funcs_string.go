@@ -0,0 +1,278 @@
+package tabp
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// stringArg returns i-th argument of a function call if it is a string.
+func stringArg(tab ReadOnlyTable, i int) (string, error) {
+	str, isString := tab.Get(i).(string)
+	if !isString {
+		return "", Error("argument is not a string")
+	}
+
+	return str, nil
+}
+
+func fnConcat(env *Env, tab ReadOnlyTable) Value {
+	var result strings.Builder
+	for i := 1; i < tab.SeqLen(); i++ {
+		str, err := stringArg(tab, i)
+		if err != nil {
+			return err
+		}
+
+		result.WriteString(str)
+	}
+
+	return env.allocString(result.String())
+}
+
+// fnStrlen returns number of runes in a string.
+func fnStrlen(_ *Env, tab ReadOnlyTable) Value {
+	str, err := stringArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	return utf8.RuneCountInString(str)
+}
+
+// fnSubstr returns runes of a string from start (inclusive) to end (exclusive).
+// End defaults to string length.
+func fnSubstr(env *Env, tab ReadOnlyTable) Value {
+	str, err := stringArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	runes := []rune(str)
+	start, isInt := tab.Get(2).(int)
+	if !isInt {
+		return Error("substring start is not an integer")
+	}
+
+	end := len(runes)
+	if tab.Has(3) {
+		end, isInt = tab.Get(3).(int)
+		if !isInt {
+			return Error("substring end is not an integer")
+		}
+	}
+
+	if start < 0 || end > len(runes) || start > end {
+		return Error("substring index out of range")
+	}
+
+	return env.allocString(string(runes[start:end]))
+}
+
+func fnSplit(env *Env, tab ReadOnlyTable) Value {
+	str, err := stringArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	sep, err := stringArg(tab, 2)
+	if err != nil {
+		return err
+	}
+
+	var result Table
+	for _, part := range strings.Split(str, sep) {
+		result.Append(part)
+	}
+	if err := env.allocTable(&result, 0); err != nil {
+		return err
+	}
+
+	return &result
+}
+
+// fnJoin concatenates strings of a table sequence using an optional separator.
+func fnJoin(env *Env, tab ReadOnlyTable) Value {
+	t, err := tableArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	sep := ""
+	if tab.Has(2) {
+		sep, err = stringArg(tab, 2)
+		if err != nil {
+			return err
+		}
+	}
+
+	parts := make([]string, t.SeqLen())
+	for i := range parts {
+		part, err := stringArg(t, i)
+		if err != nil {
+			return err
+		}
+		parts[i] = part
+	}
+
+	return env.allocString(strings.Join(parts, sep))
+}
+
+func fnUpcase(env *Env, tab ReadOnlyTable) Value {
+	str, err := stringArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	return env.allocString(strings.ToUpper(str))
+}
+
+func fnDowncase(env *Env, tab ReadOnlyTable) Value {
+	str, err := stringArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	return env.allocString(strings.ToLower(str))
+}
+
+// fnTrim removes leading and trailing white spaces or, if provided, runes
+// contained in cutset.
+func fnTrim(_ *Env, tab ReadOnlyTable) Value {
+	str, err := stringArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	if tab.Has(2) {
+		cutset, err := stringArg(tab, 2)
+		if err != nil {
+			return err
+		}
+
+		return strings.Trim(str, cutset)
+	}
+
+	return strings.TrimSpace(str)
+}
+
+func fnContains(_ *Env, tab ReadOnlyTable) Value {
+	str, err := stringArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	substr, err := stringArg(tab, 2)
+	if err != nil {
+		return err
+	}
+
+	return boolValue(strings.Contains(str, substr))
+}
+
+// fnIndex returns rune index of the first occurrence of substr in a string or
+// nil if it isn't present.
+func fnIndex(_ *Env, tab ReadOnlyTable) Value {
+	str, err := stringArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	substr, err := stringArg(tab, 2)
+	if err != nil {
+		return err
+	}
+
+	i := strings.Index(str, substr)
+	if i == -1 {
+		return nil
+	}
+
+	return utf8.RuneCountInString(str[:i])
+}
+
+// fnReplace replaces occurrences of old by new in a string. All occurrences
+// are replaced unless a maximum number of replacement is provided.
+func fnReplace(env *Env, tab ReadOnlyTable) Value {
+	str, err := stringArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	oldStr, err := stringArg(tab, 2)
+	if err != nil {
+		return err
+	}
+
+	newStr, err := stringArg(tab, 3)
+	if err != nil {
+		return err
+	}
+
+	n := -1
+	if tab.Has(4) {
+		var isInt bool
+		n, isInt = tab.Get(4).(int)
+		if !isInt {
+			return Error("replace count is not an integer")
+		}
+	}
+
+	// Account result before building it as it may be much larger than str.
+	count := strings.Count(str, oldStr)
+	if n >= 0 {
+		count = min(count, n)
+	}
+	if err := env.Alloc(len(str) + count*(len(newStr)-len(oldStr))); err != nil {
+		return err
+	}
+
+	return strings.Replace(str, oldStr, newStr, n)
+}
+
+func fnStartsWith(_ *Env, tab ReadOnlyTable) Value {
+	str, err := stringArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	prefix, err := stringArg(tab, 2)
+	if err != nil {
+		return err
+	}
+
+	return boolValue(strings.HasPrefix(str, prefix))
+}
+
+func fnEndsWith(_ *Env, tab ReadOnlyTable) Value {
+	str, err := stringArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	suffix, err := stringArg(tab, 2)
+	if err != nil {
+		return err
+	}
+
+	return boolValue(strings.HasSuffix(str, suffix))
+}
+
+// fnStringToSymbol converts a string to a symbol. Like the parser does, symbol
+// is upper cased.
+func fnStringToSymbol(_ *Env, tab ReadOnlyTable) Value {
+	str, err := stringArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	return Symbol(strings.ToUpper(str))
+}
+
+func fnSymbolToString(_ *Env, tab ReadOnlyTable) Value {
+	symbol, isSymbol := tab.Get(1).(Symbol)
+	if !isSymbol {
+		return Error("argument is not a symbol")
+	}
+
+	return string(symbol)
+}
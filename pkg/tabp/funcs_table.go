@@ -0,0 +1,186 @@
+package tabp
+
+// tableArg returns i-th argument of a function call if it is a table.
+func tableArg(tab ReadOnlyTable, i int) (*Table, error) {
+	t, isTable := tab.Get(i).(*Table)
+	if !isTable || t == nil {
+		return nil, Error("argument is not a table")
+	}
+
+	return t, nil
+}
+
+func fnTable(env *Env, tab ReadOnlyTable) Value {
+	var result Table
+	for _, v := range tab.Seq()[1:] {
+		result.Append(v)
+	}
+	for k, v := range tab.IterKVs() {
+		result.Set(k, v)
+	}
+
+	if err := env.allocTable(&result, 0); err != nil {
+		return err
+	}
+
+	return &result
+}
+
+func fnGet(_ *Env, tab ReadOnlyTable) Value {
+	t, err := tableArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	if v := t.Get(tab.Get(2)); v != nil {
+		return v
+	}
+
+	// Default value.
+	return tab.Get(3)
+}
+
+func fnSet(env *Env, tab ReadOnlyTable) Value {
+	t, err := tableArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	key := tab.Get(2)
+	if key == nil {
+		return Error("table key is nil")
+	}
+
+	value := tab.Get(3)
+	entries := t.Len()
+	t.Set(key, value)
+	if err := env.allocTable(t, entries); err != nil {
+		return err
+	}
+
+	return value
+}
+
+func fnHas(_ *Env, tab ReadOnlyTable) Value {
+	t, err := tableArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	return boolValue(t.Has(tab.Get(2)))
+}
+
+func fnAppend(env *Env, tab ReadOnlyTable) Value {
+	t, err := tableArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	entries := t.Len()
+	for _, v := range tab.Seq()[2:] {
+		t.Append(v)
+	}
+	if err := env.allocTable(t, entries); err != nil {
+		return err
+	}
+
+	return t.SeqLen()
+}
+
+func fnInsert(env *Env, tab ReadOnlyTable) Value {
+	t, err := tableArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	k, isInt := tab.Get(2).(int)
+	if !isInt {
+		return Error("insert index is not an integer")
+	}
+
+	entries := t.Len()
+	t.Insert(k, tab.Seq()[3:]...)
+	if err := env.allocTable(t, entries); err != nil {
+		return err
+	}
+
+	return t
+}
+
+func fnDelete(_ *Env, tab ReadOnlyTable) Value {
+	t, err := tableArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	key := tab.Get(2)
+	value := t.Get(key)
+	if value != nil {
+		t.Set(key, nil)
+	}
+
+	return value
+}
+
+func fnLen(_ *Env, tab ReadOnlyTable) Value {
+	t, err := tableArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	return t.Len()
+}
+
+func fnSeqLen(_ *Env, tab ReadOnlyTable) Value {
+	t, err := tableArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	return t.SeqLen()
+}
+
+func fnKVsLen(_ *Env, tab ReadOnlyTable) Value {
+	t, err := tableArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	return t.KVsLen()
+}
+
+func fnKeys(env *Env, tab ReadOnlyTable) Value {
+	t, err := tableArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	var keys Table
+	for k := range t.Iter() {
+		keys.Append(k)
+	}
+
+	if err := env.allocTable(&keys, 0); err != nil {
+		return err
+	}
+
+	return &keys
+}
+
+func fnValues(env *Env, tab ReadOnlyTable) Value {
+	t, err := tableArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	var values Table
+	for _, v := range t.Iter() {
+		values.Append(v)
+	}
+
+	if err := env.allocTable(&values, 0); err != nil {
+		return err
+	}
+
+	return &values
+}
@@ -0,0 +1,133 @@
+package tabp
+
+import (
+	"math"
+	"strconv"
+)
+
+// typeOf returns type name of the given value.
+func typeOf(v Value) Symbol {
+	switch value := v.(type) {
+	case nil:
+		return "NIL"
+	case bool:
+		return "BOOLEAN"
+	case string:
+		return "STRING"
+	case Symbol:
+		return "SYMBOL"
+	case ReadOnlyTable:
+		return "TABLE"
+	case *Function:
+		return "FUNCTION"
+	case *ErrorValue, error:
+		return "ERROR"
+	default:
+		n, err := toNumber(value)
+		if err != nil {
+			return "UNKNOWN"
+		}
+		if n.isFloat {
+			return "FLOAT"
+		}
+		return "INTEGER"
+	}
+}
+
+func fnTypeOf(_ *Env, tab ReadOnlyTable) Value {
+	return typeOf(tab.Get(1))
+}
+
+// typePredicate returns a function that returns true if its argument type is
+// one of the given types and nil otherwise.
+func typePredicate(types ...Symbol) func(*Env, ReadOnlyTable) Value {
+	return func(_ *Env, tab ReadOnlyTable) Value {
+		t := typeOf(tab.Get(1))
+		for _, typ := range types {
+			if t == typ {
+				return true
+			}
+		}
+
+		return nil
+	}
+}
+
+func fnToInt(_ *Env, tab ReadOnlyTable) Value {
+	switch value := tab.Get(1).(type) {
+	case string:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return Error("string is not a valid integer")
+		}
+		return i
+
+	default:
+		n, err := toNumber(value)
+		if err != nil {
+			return err
+		}
+
+		if !n.isFloat {
+			return n.int
+		}
+
+		// Truncate float.
+		if math.IsNaN(n.float) || n.float >= math.MaxInt || n.float < math.MinInt {
+			return Error("float is out of integer range")
+		}
+		return int(n.float)
+	}
+}
+
+func fnToFloat(_ *Env, tab ReadOnlyTable) Value {
+	switch value := tab.Get(1).(type) {
+	case string:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return Error("string is not a valid float")
+		}
+		return f
+
+	default:
+		n, err := toNumber(value)
+		if err != nil {
+			return err
+		}
+
+		return n.toFloat()
+	}
+}
+
+// toString converts a value to a string. Strings are returned as is, other
+// values are converted to their S-Expression.
+func toString(v Value) string {
+	if str, isString := v.(string); isString {
+		return str
+	}
+
+	return Sexpr(v)
+}
+
+func fnToString(env *Env, tab ReadOnlyTable) Value {
+	return env.allocString(toString(tab.Get(1)))
+}
+
+// fnParseNumber parses a string as an integer or a float.
+func fnParseNumber(_ *Env, tab ReadOnlyTable) Value {
+	str, err := stringArg(tab, 1)
+	if err != nil {
+		return err
+	}
+
+	if i, err := strconv.Atoi(str); err == nil {
+		return i
+	}
+
+	f, parseErr := strconv.ParseFloat(str, 64)
+	if parseErr != nil {
+		return Error("string is not a valid number")
+	}
+
+	return f
+}
@@ -0,0 +1,145 @@
+package tabp
+
+import "fmt"
+
+// Function define a callable tabp value.
+type Function struct {
+	name Symbol
+	fn   func(*Env, ReadOnlyTable) Value
+}
+
+// NewFunction returns a new named function value. Name is only used for
+// printing purpose, anonymous functions have an empty name.
+func NewFunction(name Symbol, fn func(*Env, ReadOnlyTable) Value) *Function {
+	return &Function{
+		name: name,
+		fn:   fn,
+	}
+}
+
+// Name returns function name.
+func (f *Function) Name() Symbol {
+	return f.name
+}
+
+// Call calls function with the given arguments. Args table sequence must start
+// with the function itself (or its name) followed by function arguments.
+func (f *Function) Call(env *Env, args ReadOnlyTable) Value {
+	return trampoline(f.fn(env, args))
+}
+
+// ToSExpr implements SExpr.
+func (f *Function) ToSExpr() string {
+	if f.name == "" {
+		return "#<LAMBDA>"
+	}
+
+	return fmt.Sprintf("#<FUNCTION %v>", f.name)
+}
+
+// funcParam define a parameter of a user defined function.
+type funcParam struct {
+	name         Symbol
+	defaultValue Value
+	// True if parameter holds remaining arguments.
+	isRest bool
+}
+
+// parseFuncParams parses parameters list of a DEFUN, LAMBDA or DEFMACRO form.
+// A parameter preceded by &REST symbol is bound to a table containing
+// remaining arguments.
+func parseFuncParams(v Value) ([]funcParam, error) {
+	paramsTable, isTable := v.(*Table)
+	if !isTable || paramsTable == nil {
+		return nil, Error("function args isn't a table")
+	}
+
+	var params []funcParam
+	isRest := false
+	for k, v := range paramsTable.Iter() {
+		if v == Symbol("&REST") {
+			isRest = true
+			continue
+		}
+
+		if symbol, isSymbol := k.(Symbol); isSymbol { // Key is symbol.
+			params = append(params, funcParam{symbol, v, false})
+		} else if symbol, isSymbol := v.(Symbol); isSymbol { // Value is symbol
+			params = append(params, funcParam{symbol, nil, isRest})
+			isRest = false
+		} else {
+			return nil, Error("function args list contains a non symbol value")
+		}
+		if isConstant(params[len(params)-1].name) {
+			return nil, Error("function args list can't bind a constant")
+		}
+	}
+	if isRest {
+		return nil, Error("&rest isn't followed by a parameter name")
+	}
+
+	return params, nil
+}
+
+// bindFuncParams defines a variable in env for each parameter using values of
+// args table. An error is returned if rest parameter table exceeds memory
+// quota.
+func bindFuncParams(env *Env, params []funcParam, argsTab ReadOnlyTable) error {
+	args := NewArgsTable(argsTab)
+
+	for _, param := range params {
+		if param.isRest {
+			rest := args.consumeRest()
+			if err := env.allocTable(rest, 0); err != nil {
+				return err
+			}
+			env.Defvar(param.name, rest)
+			continue
+		}
+
+		argVal := param.defaultValue
+		if v := args.consumeArg(param.name); v != nil {
+			argVal = v
+		}
+		env.Defvar(param.name, argVal)
+	}
+
+	return nil
+}
+
+// evalBody evaluates given forms in order. Last form is returned as a tail
+// call. Evaluation stops on first error.
+func evalBody(env *Env, body []Value) Value {
+	if len(body) == 0 {
+		return nil
+	}
+
+	for _, form := range body[:len(body)-1] {
+		result := env.Eval(form)
+		if _, isErr := result.(error); isErr {
+			return result
+		}
+	}
+
+	return tail(env, body[len(body)-1])
+}
+
+// resolveFunc returns function designated by v. v is either a *Function or a
+// symbol bound to a function.
+func (e *Env) resolveFunc(v Value) func(*Env, ReadOnlyTable) Value {
+	switch value := v.(type) {
+	case *Function:
+		return value.fn
+
+	case Symbol:
+		if fn := e.getFunc(value); fn != nil {
+			return fn
+		}
+
+		if f, isFunc := e.getVar(value).(*Function); isFunc {
+			return f.fn
+		}
+	}
+
+	return nil
+}
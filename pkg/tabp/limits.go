@@ -0,0 +1,155 @@
+package tabp
+
+import (
+	"context"
+	"unsafe"
+)
+
+var (
+	// ErrStepLimit is the cause of EvalError returned when an evaluation
+	// exceeds its maximum number of steps.
+	ErrStepLimit = Error("evaluation step limit exceeded")
+	// ErrDepthLimit is the cause of EvalError returned when an evaluation
+	// exceeds its maximum depth.
+	ErrDepthLimit = Error("maximum evaluation depth exceeded")
+	// ErrMemoryLimit is the cause of EvalError returned when an evaluation
+	// allocates more bytes than its quota.
+	ErrMemoryLimit = Error("memory quota exceeded")
+)
+
+// tableEntrySize is the approximate number of bytes allocated by a new table
+// entry: a key and a value.
+const tableEntrySize = int(unsafe.Sizeof(Value(nil)) + unsafe.Sizeof(TableEntry{}))
+
+// Limits define execution limits of an evaluation. Zero values means no
+// limit.
+//
+// Evaluating an expression (a symbol, a function call, a macro form...) is a
+// step. Depth is the number of nested evaluations: function calls, macro
+// expansions, evaluation of arguments, etc. Tail calls don't increase it.
+//
+// Allocated bytes are those accounted using Env.Alloc. Builtins account table
+// growth and strings they build. Memory is never released from the quota.
+type Limits struct {
+	MaxSteps int
+	MaxDepth int
+	MaxBytes int
+}
+
+// EvalContext is the same as Eval but evaluation is aborted when ctx is done
+// or when a limit is exceeded. EvalError cause is then respectively ctx.Err(),
+// ErrStepLimit, ErrDepthLimit or ErrMemoryLimit. Such errors can't be caught
+// using TRY. A nil ctx is treated as context.Background().
+func (e *Env) EvalContext(ctx context.Context, limits Limits, v Value) Value {
+	var result Value
+	e.withLimits(ctx, limits, func() {
+		result = e.Eval(v)
+	})
+
+	return result
+}
+
+// withLimits calls fn with the given execution limits enforced. Steps of all
+// evaluations performed by fn are counted against the same budget.
+func (e *Env) withLimits(ctx context.Context, limits Limits, fn func()) {
+	state := e.state
+	prevCtx, prevLimits, prevSteps, prevBytes, prevAbort := state.ctx, state.limits, state.steps, state.bytes, state.abort
+	defer func() {
+		state.ctx, state.limits, state.steps, state.bytes, state.abort = prevCtx, prevLimits, prevSteps, prevBytes, prevAbort
+	}()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	state.ctx, state.limits, state.steps, state.bytes, state.abort = ctx, limits, 0, 0, nil
+	fn()
+}
+
+// step records an evaluation step and returns a non nil error if evaluation
+// must be aborted.
+func (e *Env) step() error {
+	state := e.state
+	if state.abort != nil {
+		return state.abort
+	}
+	if state.ctx == nil {
+		return nil
+	}
+
+	select {
+	case <-state.ctx.Done():
+		state.abort = state.ctx.Err()
+		return state.abort
+	default:
+	}
+
+	if state.limits.MaxSteps > 0 {
+		state.steps++
+		if state.steps > state.limits.MaxSteps {
+			state.abort = ErrStepLimit
+			return state.abort
+		}
+	}
+
+	return nil
+}
+
+// Alloc accounts n bytes allocated by current evaluation. If memory quota is
+// exceeded, evaluation is aborted and ErrMemoryLimit is returned. Host
+// functions allocating memory on behalf of a program should call it.
+func (e *Env) Alloc(n int) error {
+	state := e.state
+	if state.abort != nil {
+		return state.abort
+	}
+	if state.limits.MaxBytes <= 0 || n <= 0 {
+		return nil
+	}
+
+	state.bytes += n
+	if state.bytes > state.limits.MaxBytes {
+		state.abort = ErrMemoryLimit
+		return state.abort
+	}
+
+	return nil
+}
+
+// allocTable accounts growth of a table that had the given number of entries.
+func (e *Env) allocTable(tab *Table, entries int) error {
+	return e.Alloc((tab.Len() - entries) * tableEntrySize)
+}
+
+// allocString accounts allocation of a string built by a builtin and returns
+// it.
+func (e *Env) allocString(str string) Value {
+	if err := e.Alloc(len(str)); err != nil {
+		return err
+	}
+
+	return str
+}
+
+// enter records entering a nested evaluation. ErrDepthLimit is returned if
+// maximum evaluation depth is exceeded.
+func (e *Env) enter() error {
+	state := e.state
+	if state.limits.MaxDepth > 0 && state.depth >= state.limits.MaxDepth {
+		state.abort = ErrDepthLimit
+		return state.abort
+	}
+
+	state.depth++
+	return nil
+}
+
+// leave records leaving a nested evaluation.
+func (e *Env) leave() {
+	e.state.depth--
+}
+
+// aborted returns whether current evaluation was aborted because a limit was
+// exceeded.
+func (e *Env) aborted() bool {
+	return e.state.abort != nil
+}
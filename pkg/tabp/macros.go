@@ -1,52 +1,82 @@
 package tabp
 
+import "iter"
+
 func macroQuote(_ *Env, tab ReadOnlyTable) Value {
 	return tab.Get(1)
 }
 
 func macroQuasiQuote(env *Env, tab ReadOnlyTable) Value {
-	qquoted := tab.Get(1)
-	if tab, isTab := qquoted.(*Table); isTab {
-		qquoted = macroQuasiQuoteRecursive(env, tab)
-	}
-
-	return qquoted
+	return quasiQuote(env, tab.Get(1))
 }
 
-func macroQuasiQuoteRecursive(env *Env, tab *Table) Value {
+// quasiQuote returns v as is unless it is a table. Tables are copied
+// recursively: UNQUOTE forms are replaced by their evaluation and
+// UNQUOTE-SPLICING forms within table sequences are replaced by the sequence
+// of their evaluation, key / value pairs are merged in the table. Quasiquoted
+// table is never modified so it can be evaluated multiple times.
+func quasiQuote(env *Env, v Value) Value {
+	tab, isTab := v.(*Table)
+	if !isTab || tab == nil {
+		return v
+	}
+
 	if symbol, isSymbol := tab.Get(0).(Symbol); isSymbol {
-		if symbol == Symbol("UNQUOTE") {
-			unquoted := env.Eval(tab.Get(1))
-			if t, isTab := unquoted.(*Table); isTab {
-				tab = t
-			} else {
-				return unquoted
-			}
+		switch symbol {
+		case Symbol("UNQUOTE"):
+			return env.Eval(tab.Get(1))
+		case Symbol("UNQUOTE-SPLICING"):
+			return Error("unquote-splicing outside of a table sequence")
 		}
 	}
 
-	for k, v := range tab.Iter() {
-		var (
-			newK = k
-			newV = v
-		)
+	var result Table
+	for _, v := range tab.Seq() {
+		// Splice.
+		if spliceTab, isTab := v.(*Table); isTab && spliceTab.Get(0) == Symbol("UNQUOTE-SPLICING") {
+			switch spliced := env.Eval(spliceTab.Get(1)).(type) {
+			case nil:
+			case error:
+				return spliced
+			case ReadOnlyTable:
+				for _, v := range spliced.Seq() {
+					result.Append(v)
+				}
+				for k, v := range spliced.IterKVs() {
+					result.Set(k, v)
+				}
+			default:
+				return Error("unquote-splicing value isn't a table")
+			}
+			continue
+		}
 
-		if tabK, isTab := k.(*Table); isTab {
-			newK = macroQuasiQuoteRecursive(env, tabK)
+		newV := quasiQuote(env, v)
+		if _, isErr := newV.(error); isErr {
+			return newV
 		}
+		result.Append(newV)
+	}
 
-		if tabV, isTab := v.(*Table); isTab {
-			newV = macroQuasiQuoteRecursive(env, tabV)
+	for k, v := range tab.IterKVs() {
+		newK := quasiQuote(env, k)
+		if _, isErr := newK.(error); isErr {
+			return newK
 		}
 
-		// Update table.
-		if newK != k {
-			tab.Set(k, nil)
+		newV := quasiQuote(env, v)
+		if _, isErr := newV.(error); isErr {
+			return newV
 		}
-		tab.Set(newK, newV)
+
+		result.Set(newK, newV)
 	}
 
-	return tab
+	if err := env.allocTable(&result, 0); err != nil {
+		return err
+	}
+
+	return &result
 }
 
 func macroDefun(env *Env, tab ReadOnlyTable) Value {
@@ -55,42 +85,67 @@ func macroDefun(env *Env, tab ReadOnlyTable) Value {
 		return Error("function name isn't a symbol")
 	}
 
-	funcArgsTable, isTable := tab.Get(2).(*Table)
-	if !isTable || funcArgsTable == nil {
-		return Error("function args isn't a table")
+	funcParams, err := parseFuncParams(tab.Get(2))
+	if err != nil {
+		return err
 	}
 
-	type funcArg struct {
-		name         Symbol
-		defaultValue Value
+	funBody := tab.Seq()[3:]
+
+	// Function body is evaluated within defining environment, not the caller's
+	// one.
+	env.Defun(name, func(_ *Env, argsTab ReadOnlyTable) Value {
+		funcEnv := NewEnv(env)
+		if err := bindFuncParams(&funcEnv, funcParams, argsTab); err != nil {
+			return err
+		}
+
+		return evalBody(&funcEnv, funBody)
+	})
+
+	return name
+}
+
+func macroLambda(env *Env, tab ReadOnlyTable) Value {
+	funcParams, err := parseFuncParams(tab.Get(1))
+	if err != nil {
+		return err
 	}
 
-	var funcArgs []funcArg
-	for k, v := range funcArgsTable.Iter() {
-		if symbol, isSymbol := k.(Symbol); isSymbol { // Key is symbol.
-			funcArgs = append(funcArgs, funcArg{symbol, v})
-		} else if symbol, isSymbol := v.(Symbol); isSymbol { // Value is symbol
-			funcArgs = append(funcArgs, funcArg{symbol, nil})
-		} else {
-			return Error("args list of function in defun call is not a symbol")
+	funBody := tab.Seq()[2:]
+
+	return NewFunction("", func(_ *Env, argsTab ReadOnlyTable) Value {
+		funcEnv := NewEnv(env)
+		if err := bindFuncParams(&funcEnv, funcParams, argsTab); err != nil {
+			return err
 		}
+
+		return evalBody(&funcEnv, funBody)
+	})
+}
+
+func macroDefmacro(env *Env, tab ReadOnlyTable) Value {
+	name, isSymbol := tab.Get(1).(Symbol)
+	if !isSymbol {
+		return Error("macro name isn't a symbol")
 	}
 
-	funBody := tab.Get(3)
+	macroParams, err := parseFuncParams(tab.Get(2))
+	if err != nil {
+		return err
+	}
 
-	env.Defun(name, func(env *Env, argsTab ReadOnlyTable) Value {
-		funcEnv := newFuncEnv(env)
-		args := NewArgsTable(argsTab)
+	macroBody := tab.Seq()[3:]
 
-		for _, funcArg := range funcArgs {
-			argVal := funcArg.defaultValue
-			if v := args.consumeArg(funcArg.name); v != nil {
-				argVal = v
-			}
-			funcEnv.Defvar(funcArg.name, argVal)
+	// Arguments are bound unevaluated and body is evaluated within defining
+	// environment.
+	env.defmacroExpander(name, func(form ReadOnlyTable) Value {
+		macroEnv := NewEnv(env)
+		if err := bindFuncParams(&macroEnv, macroParams, form); err != nil {
+			return err
 		}
 
-		return funcEnv.Eval(funBody)
+		return trampoline(evalBody(&macroEnv, macroBody))
 	})
 
 	return name
@@ -101,17 +156,457 @@ func macroDefvar(env *Env, tab ReadOnlyTable) Value {
 	if !isSymbol {
 		return Error("defvar variable name must be a symbol")
 	}
+	if isConstant(name) {
+		return Error("defvar can't redefine a constant")
+	}
+
+	value := env.Eval(tab.Get(2))
+	if _, isErr := value.(error); isErr {
+		return value
+	}
 
-	env.Defvar(name, tab.Get(2))
+	env.Defvar(name, value)
 
 	return name
 }
 
+func macroSetq(env *Env, tab ReadOnlyTable) Value {
+	if tab.SeqLen()%2 != 1 {
+		return Error("setq expects name and value pairs")
+	}
+
+	var value Value
+	for i := 1; i < tab.SeqLen(); i += 2 {
+		name, isSymbol := tab.Get(i).(Symbol)
+		if !isSymbol {
+			return Error("setq variable name must be a symbol")
+		}
+		if isConstant(name) {
+			return Error("setq can't assign a constant")
+		}
+
+		value = env.Eval(tab.Get(i + 1))
+		if _, isErr := value.(error); isErr {
+			return value
+		}
+
+		if !env.Setvar(name, value) {
+			return Error("variable is not bound")
+		}
+	}
+
+	return value
+}
+
 func macroIf(env *Env, tab ReadOnlyTable) Value {
 	cond := env.Eval(tab.Get(1))
-	if cond != nil && cond != false {
-		return env.Eval(tab.Get(2))
+	if _, isErr := cond.(error); isErr {
+		return cond
+	}
+
+	if isTruthy(cond) {
+		return tail(env, tab.Get(2))
+	}
+
+	return tail(env, tab.Get(3))
+}
+
+func macroWhen(env *Env, tab ReadOnlyTable) Value {
+	if tab.SeqLen() < 2 {
+		return Error("when condition is missing")
+	}
+
+	cond := env.Eval(tab.Get(1))
+	if _, isErr := cond.(error); isErr {
+		return cond
+	}
+	if !isTruthy(cond) {
+		return nil
+	}
+
+	return evalBody(env, tab.Seq()[2:])
+}
+
+func macroUnless(env *Env, tab ReadOnlyTable) Value {
+	if tab.SeqLen() < 2 {
+		return Error("unless condition is missing")
+	}
+
+	cond := env.Eval(tab.Get(1))
+	if _, isErr := cond.(error); isErr {
+		return cond
+	}
+	if isTruthy(cond) {
+		return nil
+	}
+
+	return evalBody(env, tab.Seq()[2:])
+}
+
+func macroCond(env *Env, tab ReadOnlyTable) Value {
+	for _, clause := range tab.Seq()[1:] {
+		clauseTab, isTable := clause.(*Table)
+		if !isTable || clauseTab == nil || clauseTab.SeqLen() == 0 {
+			return Error("cond clause isn't a non empty table")
+		}
+
+		cond := env.Eval(clauseTab.Get(0))
+		if _, isErr := cond.(error); isErr {
+			return cond
+		}
+		if !isTruthy(cond) {
+			continue
+		}
+
+		// Clause without body returns condition value.
+		if clauseTab.SeqLen() == 1 {
+			return cond
+		}
+
+		return evalBody(env, clauseTab.Seq()[1:])
+	}
+
+	return nil
+}
+
+func macroCase(env *Env, tab ReadOnlyTable) Value {
+	if tab.SeqLen() < 2 {
+		return Error("case key is missing")
+	}
+
+	key := env.Eval(tab.Get(1))
+	if _, isErr := key.(error); isErr {
+		return key
+	}
+
+	for _, clause := range tab.Seq()[2:] {
+		clauseTab, isTable := clause.(*Table)
+		if !isTable || clauseTab == nil || clauseTab.SeqLen() == 0 {
+			return Error("case clause isn't a non empty table")
+		}
+
+		if caseClauseMatch(key, clauseTab.Get(0)) {
+			return evalBody(env, clauseTab.Seq()[1:])
+		}
+	}
+
+	return nil
+}
+
+// caseClauseMatch returns whether key matches keys of a CASE clause. Keys is
+// either a single literal, a table of literals or T / OTHERWISE symbol that
+// matches any key.
+func caseClauseMatch(key Value, keys Value) bool {
+	switch k := keys.(type) {
+	case Symbol:
+		if k == "T" || k == "OTHERWISE" {
+			return true
+		}
+
+	case *Table:
+		for _, v := range k.Seq() {
+			if ValuesEqual(key, v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return ValuesEqual(key, keys)
+}
+
+func macroAnd(env *Env, tab ReadOnlyTable) Value {
+	args := tab.Seq()[1:]
+	if len(args) == 0 {
+		return true
+	}
+
+	for _, arg := range args[:len(args)-1] {
+		v := env.Eval(arg)
+		if _, isErr := v.(error); isErr || !isTruthy(v) {
+			return v
+		}
+	}
+
+	return tail(env, args[len(args)-1])
+}
+
+func macroOr(env *Env, tab ReadOnlyTable) Value {
+	args := tab.Seq()[1:]
+	if len(args) == 0 {
+		return nil
+	}
+
+	for _, arg := range args[:len(args)-1] {
+		v := env.Eval(arg)
+		if _, isErr := v.(error); isErr || isTruthy(v) {
+			return v
+		}
+	}
+
+	return tail(env, args[len(args)-1])
+}
+
+func macroProgn(env *Env, tab ReadOnlyTable) Value {
+	return evalBody(env, tab.Seq()[1:])
+}
+
+func macroLet(env *Env, tab ReadOnlyTable) Value {
+	return evalLet(env, tab, false)
+}
+
+func macroLetStar(env *Env, tab ReadOnlyTable) Value {
+	return evalLet(env, tab, true)
+}
+
+// evalLet evaluates a LET or LET* form. Bindings are either a sequence of
+// (name value) tables / symbols or name: value pairs. If sequential is true,
+// bindings are evaluated in order within the new environment so they can
+// refer to previous bindings. Keyed bindings are evaluated after the sequence
+// ones in an unspecified order.
+func evalLet(env *Env, tab ReadOnlyTable, sequential bool) Value {
+	bindings, isTable := tab.Get(1).(*Table)
+	if !isTable || bindings == nil {
+		return Error("let bindings isn't a table")
+	}
+
+	letEnv := NewEnv(env)
+	valueEnv := env
+	if sequential {
+		valueEnv = &letEnv
+	}
+
+	bind := func(name Value, expr Value) Value {
+		symbol, isSymbol := name.(Symbol)
+		if !isSymbol {
+			return Error("let binding name isn't a symbol")
+		}
+		if isConstant(symbol) {
+			return Error("let can't bind a constant")
+		}
+
+		value := valueEnv.Eval(expr)
+		if _, isErr := value.(error); isErr {
+			return value
+		}
+
+		letEnv.Defvar(symbol, value)
+		return nil
+	}
+
+	for _, binding := range bindings.Seq() {
+		var err Value
+		switch b := binding.(type) {
+		case Symbol:
+			err = bind(b, nil)
+		case *Table:
+			err = bind(b.Get(0), b.Get(1))
+		default:
+			err = Error("let binding isn't a symbol or a table")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for k, v := range bindings.IterKVs() {
+		if err := bind(k, v); err != nil {
+			return err
+		}
+	}
+
+	return evalBody(&letEnv, tab.Seq()[2:])
+}
+
+func macroWhile(env *Env, tab ReadOnlyTable) Value {
+	if tab.SeqLen() < 2 {
+		return Error("while condition is missing")
+	}
+
+	body := tab.Seq()[2:]
+
+	for {
+		cond := env.Eval(tab.Get(1))
+		if _, isErr := cond.(error); isErr {
+			return cond
+		}
+		if !isTruthy(cond) {
+			return nil
+		}
+
+		result := trampoline(evalBody(env, body))
+		if _, isErr := result.(error); isErr {
+			return result
+		}
+	}
+}
+
+func macroDotimes(env *Env, tab ReadOnlyTable) Value {
+	spec, isTable := tab.Get(1).(*Table)
+	if !isTable || spec == nil {
+		return Error("dotimes spec isn't a table")
+	}
+
+	name, isSymbol := spec.Get(0).(Symbol)
+	if !isSymbol {
+		return Error("dotimes variable name must be a symbol")
+	}
+	if isConstant(name) {
+		return Error("dotimes can't bind a constant")
+	}
+
+	countV := env.Eval(spec.Get(1))
+	if _, isErr := countV.(error); isErr {
+		return countV
+	}
+	count, isInt := countV.(int)
+	if !isInt {
+		return Error("dotimes count must be an integer")
+	}
+
+	body := tab.Seq()[2:]
+	for i := 0; i < count; i++ {
+		// New environment for each iteration so closures capture current value.
+		loopEnv := NewEnv(env)
+		loopEnv.Defvar(name, i)
+
+		result := trampoline(evalBody(&loopEnv, body))
+		if _, isErr := result.(error); isErr {
+			return result
+		}
+	}
+
+	// Optional result form.
+	resultEnv := NewEnv(env)
+	resultEnv.Defvar(name, count)
+	return tail(&resultEnv, spec.Get(2))
+}
+
+func macroForeach(env *Env, tab ReadOnlyTable) Value {
+	return evalForeach(env, tab, ReadOnlyTable.Iter)
+}
+
+func macroForeachSeq(env *Env, tab ReadOnlyTable) Value {
+	return evalForeach(env, tab, func(t ReadOnlyTable) iter.Seq2[Value, Value] {
+		return func(yield func(k, v Value) bool) {
+			for i, v := range t.IterSeq() {
+				if !yield(i, v) {
+					return
+				}
+			}
+		}
+	})
+}
+
+func macroForeachKVs(env *Env, tab ReadOnlyTable) Value {
+	return evalForeach(env, tab, ReadOnlyTable.IterKVs)
+}
+
+// evalForeach evaluates a FOREACH like form: (foreach (k v table) body...).
+// Body is evaluated for each key/value pair returned by iterFn.
+func evalForeach(env *Env, tab ReadOnlyTable, iterFn func(ReadOnlyTable) iter.Seq2[Value, Value]) Value {
+	spec, isTable := tab.Get(1).(*Table)
+	if !isTable || spec == nil || spec.SeqLen() != 3 {
+		return Error("foreach spec must be a (key value table) table")
+	}
+
+	keyName, isSymbol := spec.Get(0).(Symbol)
+	if !isSymbol {
+		return Error("foreach key variable name must be a symbol")
+	}
+	valueName, isSymbol := spec.Get(1).(Symbol)
+	if !isSymbol {
+		return Error("foreach value variable name must be a symbol")
+	}
+	if isConstant(keyName) || isConstant(valueName) {
+		return Error("foreach can't bind a constant")
+	}
+
+	iterated := env.Eval(spec.Get(2))
+	if _, isErr := iterated.(error); isErr {
+		return iterated
+	}
+	if iterated == nil {
+		return nil
+	}
+	iterTab, isTable := iterated.(ReadOnlyTable)
+	if !isTable {
+		return Error("foreach can't iterate over a non table value")
+	}
+
+	body := tab.Seq()[2:]
+	for k, v := range iterFn(iterTab) {
+		// New environment for each iteration so closures capture current values.
+		loopEnv := NewEnv(env)
+		loopEnv.Defvar(keyName, k)
+		loopEnv.Defvar(valueName, v)
+
+		result := trampoline(evalBody(&loopEnv, body))
+		if _, isErr := result.(error); isErr {
+			return result
+		}
+	}
+
+	return nil
+}
+
+// macroTry evaluates a TRY form: (try body... (catch err handler...)
+// (finally cleanup...)). If body returns an error, handler is evaluated with
+// err bound to the caught error. Cleanup forms are always evaluated last, unless
+// an execution limit is exceeded. Clauses are validated before body is
+// evaluated.
+func macroTry(env *Env, tab ReadOnlyTable) Value {
+	var (
+		body        []Value
+		catchClause *Table
+		catchName   Symbol
+		finallyBody []Value
+	)
+	for _, form := range tab.Seq()[1:] {
+		clause, isTable := form.(*Table)
+		if isTable && clause != nil {
+			switch clause.Get(0) {
+			case Symbol("CATCH"):
+				name, isSymbol := clause.Get(1).(Symbol)
+				if !isSymbol {
+					return Error("catch error variable name must be a symbol")
+				}
+				if isConstant(name) {
+					return Error("catch can't bind a constant")
+				}
+				catchClause, catchName = clause, name
+				continue
+			case Symbol("FINALLY"):
+				finallyBody = clause.Seq()[1:]
+				continue
+			}
+		}
+
+		if catchClause != nil || finallyBody != nil {
+			return Error("try body must precede catch and finally clauses")
+		}
+		body = append(body, form)
+	}
+
+	result := trampoline(evalBody(env, body))
+
+	// Exceeded execution limits can't be caught and cleanup is skipped.
+	if env.aborted() {
+		return result
+	}
+
+	if err, isErr := result.(error); isErr && catchClause != nil {
+		catchEnv := NewEnv(env)
+		catchEnv.Defvar(catchName, &ErrorValue{Err: err})
+		result = trampoline(evalBody(&catchEnv, catchClause.Seq()[2:]))
+	}
+
+	if finallyBody != nil {
+		cleanup := trampoline(evalBody(env, finallyBody))
+		if _, isErr := cleanup.(error); isErr {
+			return cleanup
+		}
 	}
 
-	return env.Eval(tab.Get(3))
+	return result
 }
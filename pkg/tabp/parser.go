@@ -12,10 +12,11 @@ import (
 
 // Parser define a Tabp parser.
 type Parser struct {
-	reader  *bufio.Reader
-	cursor  Position
-	symbols map[Symbol]any
-	unread  bool
+	reader *bufio.Reader
+	cursor Position
+	// Cursor before last read rune, restored when it is unread.
+	prevCursor Position
+	symbols    map[Symbol]any
 }
 
 type ParseError struct {
@@ -33,6 +34,12 @@ func NewParser(r io.Reader) Parser {
 	}
 }
 
+// SetSource sets name of parsed source (e.g. a file path). It is reported in
+// positions.
+func (p *Parser) SetSource(name string) {
+	p.cursor.source = name
+}
+
 // Error implements error.
 func (pe ParseError) Error() string {
 	return fmt.Sprintf("failed to parse tabp expression at %v: %v", pe.Position, pe.Cause)
@@ -43,6 +50,19 @@ func (pe ParseError) Unwrap() error {
 	return pe.Cause
 }
 
+// unexpectedEOF turns an io.EOF error returned while parsing an unfinished
+// expression into an io.ErrUnexpectedEOF one. Other errors are returned as is.
+func unexpectedEOF(err ParseError, msg string) ParseError {
+	if err.Cause != io.EOF {
+		return err
+	}
+
+	return ParseError{
+		Cause:    fmt.Errorf("%v: %w", msg, io.ErrUnexpectedEOF),
+		Position: err.Position,
+	}
+}
+
 func (p *Parser) readRune() (rune, ParseError) {
 	r, size, err := p.reader.ReadRune()
 	if err != nil {
@@ -52,26 +72,26 @@ func (p *Parser) readRune() (rune, ParseError) {
 		}
 	}
 
-	if !p.unread {
-		p.cursor.byte += size
-		p.cursor.col++
+	p.prevCursor = p.cursor
+	p.cursor.byte += size
+	p.cursor.col++
 
-		if r == '\n' {
-			p.cursor.line++
-			p.cursor.col = 0
-		}
+	if r == '\n' {
+		p.cursor.line++
+		p.cursor.col = 0
 	}
-	p.unread = false
 
 	return r, ParseError{}
 }
 
+// mustUnreadRune unreads last read rune and rewinds cursor. It panics if
+// previous operation wasn't a successful readRune.
 func (p *Parser) mustUnreadRune() {
 	err := p.reader.UnreadRune()
 	if err != nil {
 		panic(err)
 	}
-	p.unread = true
+	p.cursor = p.prevCursor
 }
 
 func (p *Parser) peekRune() (rune, ParseError) {
@@ -106,8 +126,8 @@ func (p *Parser) collectBytesWhile(predicate func(r rune) bool, buf []byte) ([]b
 	for {
 		r, err := p.readRune()
 		if err.Cause != nil {
-			// EOF error after reading some rune.
-			if err.Cause == io.EOF && lastRune != 0 {
+			// EOF error after collecting some rune.
+			if err.Cause == io.EOF && len(buf) > 0 {
 				// Return collected runes as EOF will be returned on next call.
 				break
 			}
@@ -165,26 +185,49 @@ func (p *Parser) Parse() (Value, ParseError) {
 		}
 	}
 
+	// Position before first rune of the value.
+	start := p.prevCursor
+
+	var (
+		tab    *Table
+		tabErr ParseError
+	)
+	switch r {
 	// Quote.
-	if r == '\'' {
-		return p.parseQuote()
-	}
+	case '\'':
+		tab, tabErr = p.parseQuote()
 
 	// Quasi quote.
-	if r == '`' {
-		return p.parseQuasiQuote()
-	}
+	case '`':
+		tab, tabErr = p.parseQuasiQuote()
 
 	// Unquote.
-	if r == ',' {
-		return p.parseUnquote()
-	}
+	case ',':
+		// Unquote splicing.
+		if next, err := p.peekRune(); err.Cause == nil && next == '@' {
+			p.mustSkip(1)
+			tab, tabErr = p.parseUnquoteSplicing()
+		} else {
+			tab, tabErr = p.parseUnquote()
+		}
 
 	// Table.
-	if r == '(' {
-		return p.parseTable()
+	case '(':
+		tab, tabErr = p.parseTable()
+
+	default:
+		return p.parseAtom(r)
 	}
+	if tabErr.Cause != nil {
+		return nil, tabErr
+	}
+
+	tab.pos = start
+	return tab, ParseError{}
+}
 
+// parseAtom parses a number, a string or a symbol starting with the given rune.
+func (p *Parser) parseAtom(r rune) (Value, ParseError) {
 	// Number.
 	if r == '+' || r == '-' || r == '.' || unicode.IsDigit(r) {
 		return p.parseNumber(r)
@@ -215,14 +258,7 @@ func (p *Parser) parseTableValues(tab *Table) ParseError {
 		// Skip whitespaces.
 		r, err := p.skipWhile(unicode.IsSpace)
 		if err.Cause != nil {
-			if err.Cause == io.EOF {
-				return ParseError{
-					Cause:    fmt.Errorf("unexpected EOF, table closing parenthesis missing: %w", err),
-					Position: p.cursor,
-				}
-			}
-
-			return err
+			return unexpectedEOF(err, "table closing parenthesis missing")
 		}
 
 		// End of table.
@@ -235,13 +271,13 @@ func (p *Parser) parseTableValues(tab *Table) ParseError {
 		// Parse values.
 		value, err := p.Parse()
 		if err.Cause != nil {
-			return err
+			return unexpectedEOF(err, "table closing parenthesis missing")
 		}
 
 		// Skip whitespaces.
 		r, err = p.skipWhile(unicode.IsSpace)
 		if err.Cause != nil {
-			return err
+			return unexpectedEOF(err, "table closing parenthesis missing")
 		}
 
 		// Value is a key.
@@ -249,7 +285,7 @@ func (p *Parser) parseTableValues(tab *Table) ParseError {
 			key := value
 			value, err = p.Parse()
 			if err.Cause != nil {
-				return err
+				return unexpectedEOF(err, "table value missing")
 			}
 
 			tab.Set(key, value)
@@ -319,7 +355,13 @@ func (p *Parser) parseString(r rune) (string, ParseError) {
 		return r != '"'
 	}, buf)
 	if parseErr.Cause != nil {
-		return "", parseErr
+		return "", unexpectedEOF(parseErr, "string closing quote missing")
+	}
+	if r != '"' {
+		return "", unexpectedEOF(ParseError{
+			Cause:    io.EOF,
+			Position: p.cursor,
+		}, "string closing quote missing")
 	}
 
 	buf = utf8.AppendRune(buf, r)
@@ -406,7 +448,7 @@ func (p *Parser) parseQuote() (*Table, ParseError) {
 	// Parse quoted value.
 	value, err := p.Parse()
 	if err.Cause != nil {
-		return nil, err
+		return nil, unexpectedEOF(err, "quoted value missing")
 	}
 
 	tab.Append(value)
@@ -421,7 +463,7 @@ func (p *Parser) parseQuasiQuote() (*Table, ParseError) {
 	// Parse quoted value.
 	value, err := p.Parse()
 	if err.Cause != nil {
-		return nil, err
+		return nil, unexpectedEOF(err, "quoted value missing")
 	}
 
 	tab.Append(value)
@@ -436,7 +478,22 @@ func (p *Parser) parseUnquote() (*Table, ParseError) {
 	// Parse quoted value.
 	value, err := p.Parse()
 	if err.Cause != nil {
-		return nil, err
+		return nil, unexpectedEOF(err, "quoted value missing")
+	}
+
+	tab.Append(value)
+
+	return &tab, ParseError{}
+}
+
+func (p *Parser) parseUnquoteSplicing() (*Table, ParseError) {
+	var tab Table
+	tab.Append(Symbol("UNQUOTE-SPLICING"))
+
+	// Parse quoted value.
+	value, err := p.Parse()
+	if err.Cause != nil {
+		return nil, unexpectedEOF(err, "quoted value missing")
 	}
 
 	tab.Append(value)
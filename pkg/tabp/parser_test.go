@@ -2,6 +2,7 @@ package tabp
 
 import (
 	"bytes"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -150,6 +151,26 @@ func TestParser(t *testing.T) {
 			})
 		})
 
+		t.Run("Unquote", func(t *testing.T) {
+			parser := NewParser(bytes.NewBufferString("`(a ,b ,@c)"))
+
+			v, err := parser.Parse()
+			require.NoError(t, err.Cause)
+			require.IsType(t, &Table{}, v)
+			qquote := v.(*Table)
+			require.Equal(t, Symbol("QUASIQUOTE"), qquote.Get(0))
+
+			require.IsType(t, &Table{}, qquote.Get(1))
+			tab := qquote.Get(1).(*Table)
+			require.Equal(t, Symbol("A"), tab.Get(0))
+			require.IsType(t, &Table{}, tab.Get(1))
+			require.Equal(t, Symbol("UNQUOTE"), tab.Get(1).(*Table).Get(0))
+			require.Equal(t, Symbol("B"), tab.Get(1).(*Table).Get(1))
+			require.IsType(t, &Table{}, tab.Get(2))
+			require.Equal(t, Symbol("UNQUOTE-SPLICING"), tab.Get(2).(*Table).Get(0))
+			require.Equal(t, Symbol("C"), tab.Get(2).(*Table).Get(1))
+		})
+
 		t.Run("Empty", func(t *testing.T) {
 			parser := NewParser(bytes.NewBufferString(`()`))
 
@@ -188,4 +209,60 @@ func TestParser(t *testing.T) {
 		require.NoError(t, err.Cause)
 		require.Equal(t, Symbol("HELLO"), v)
 	})
+	t.Run("Position", func(t *testing.T) {
+		t.Run("MultiByteRunesAndNewlines", func(t *testing.T) {
+			parser := NewParser(bytes.NewBufferString("héllo\n  wörld"))
+
+			v, err := parser.Parse()
+			require.NoError(t, err.Cause)
+			require.Equal(t, Symbol("HÉLLO"), v)
+			// Newline was peeked but not consumed.
+			require.Equal(t, Position{byte: 6, line: 1, col: 5}, parser.cursor)
+
+			v, err = parser.Parse()
+			require.NoError(t, err.Cause)
+			require.Equal(t, Symbol("WÖRLD"), v)
+			require.Equal(t, Position{byte: 15, line: 2, col: 7}, parser.cursor)
+		})
+
+		t.Run("UnreadNewline", func(t *testing.T) {
+			parser := NewParser(bytes.NewBufferString("a\nb"))
+
+			_, err := parser.readRune()
+			require.NoError(t, err.Cause)
+
+			r, err := parser.peekRune()
+			require.NoError(t, err.Cause)
+			require.Equal(t, '\n', r)
+			require.Equal(t, Position{byte: 1, line: 1, col: 1}, parser.cursor)
+
+			parser.mustSkip(2)
+			require.Equal(t, Position{byte: 3, line: 2, col: 1}, parser.cursor)
+		})
+
+		t.Run("Tables", func(t *testing.T) {
+			parser := NewParser(bytes.NewBufferString("(é\n (b ,@c))"))
+			parser.SetSource("test.tabp")
+
+			v, err := parser.Parse()
+			require.NoError(t, err.Cause)
+
+			outer := v.(*Table)
+			inner := outer.Get(1).(*Table)
+			splice := inner.Get(1).(*Table)
+			require.Equal(t, Position{source: "test.tabp", byte: 0, line: 1, col: 0}, outer.Position())
+			require.Equal(t, Position{source: "test.tabp", byte: 5, line: 2, col: 1}, inner.Position())
+			require.Equal(t, Position{source: "test.tabp", byte: 8, line: 2, col: 4}, splice.Position())
+			require.Equal(t, "test.tabp:1:1 (0 bytes)", outer.Position().String())
+			require.Equal(t, "test.tabp:2:2 (5 bytes)", inner.Position().String())
+		})
+
+		t.Run("Error", func(t *testing.T) {
+			parser := NewParser(bytes.NewBufferString("(a\n\"é"))
+
+			_, err := parser.Parse()
+			require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+			require.Equal(t, Position{byte: 6, line: 2, col: 2}, err.Position)
+		})
+	})
 }
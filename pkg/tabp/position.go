@@ -3,7 +3,11 @@ package tabp
 import "fmt"
 
 // Position holds byte, line and column position of a cursor in a byte stream.
+// Byte is the number of bytes before cursor, line starts at 1 and column is
+// the number of runes before cursor on current line. Columns are printed
+// 1-based by String, as editors and compilers do.
 type Position struct {
+	source          string
 	byte, line, col int
 }
 
@@ -16,7 +20,16 @@ func NewPosition() Position {
 	}
 }
 
+// IsValid returns whether position is known. Zero Position is invalid.
+func (p Position) IsValid() bool {
+	return p.line > 0
+}
+
 // String implements fmt.Stringer.
 func (p Position) String() string {
-	return fmt.Sprintf("%v:%v (%v bytes)", p.line, p.col, p.byte)
+	if p.source != "" {
+		return fmt.Sprintf("%v:%v:%v (%v bytes)", p.source, p.line, p.col+1, p.byte)
+	}
+
+	return fmt.Sprintf("%v:%v (%v bytes)", p.line, p.col+1, p.byte)
 }
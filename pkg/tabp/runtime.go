@@ -0,0 +1,121 @@
+package tabp
+
+import (
+	"bytes"
+	"context"
+	"io"
+)
+
+// Runtime is an embeddable tabp interpreter. It owns a root environment with
+// tabp standard library loaded. Definitions are kept between evaluations so
+// multiple snippets can be evaluated against the same state.
+//
+// A Runtime must not be used concurrently.
+type Runtime struct {
+	env    Env
+	limits Limits
+}
+
+// NewRuntime returns a new runtime with tabp standard library loaded.
+func NewRuntime() *Runtime {
+	return &Runtime{env: NewStdEnv()}
+}
+
+// NewSandboxRuntime returns a new runtime with sandbox standard library
+// modules loaded. It is suitable for untrusted programs as they can't perform
+// any I/O.
+func NewSandboxRuntime() *Runtime {
+	return &Runtime{env: NewSandboxEnv()}
+}
+
+// NewModulesRuntime returns a new runtime with only the given standard library
+// modules loaded.
+func NewModulesRuntime(modules ...Module) *Runtime {
+	return &Runtime{env: NewModulesEnv(modules...)}
+}
+
+// Env returns root environment of the runtime. It can be used to define host
+// functions, macros and variables.
+func (rt *Runtime) Env() *Env {
+	return &rt.env
+}
+
+// SetLimits sets execution limits enforced on each evaluation. See Limits.
+func (rt *Runtime) SetLimits(limits Limits) {
+	rt.limits = limits
+}
+
+// EvalValue evaluates a single parsed value and returns its result.
+func (rt *Runtime) EvalValue(v Value) (Value, error) {
+	return rt.EvalValueContext(context.Background(), v)
+}
+
+// EvalValueContext is the same as EvalValue but evaluation is aborted when ctx
+// is done. See Env.EvalContext.
+func (rt *Runtime) EvalValueContext(ctx context.Context, v Value) (Value, error) {
+	result := rt.env.EvalContext(ctx, rt.limits, v)
+	if err, isErr := result.(error); isErr {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// EvalString parses and evaluates all expressions of src. Value of the last
+// expression is returned.
+func (rt *Runtime) EvalString(src string) (Value, error) {
+	return rt.EvalStringContext(context.Background(), src)
+}
+
+// EvalStringContext is the same as EvalString but evaluation is aborted when
+// ctx is done. See Env.EvalContext.
+func (rt *Runtime) EvalStringContext(ctx context.Context, src string) (Value, error) {
+	return rt.EvalSourceContext(ctx, "", bytes.NewBufferString(src))
+}
+
+// EvalReader parses and evaluates all expressions read from r. Value of the
+// last expression is returned.
+func (rt *Runtime) EvalReader(r io.Reader) (Value, error) {
+	return rt.EvalSourceContext(context.Background(), "", r)
+}
+
+// EvalReaderContext is the same as EvalReader but evaluation is aborted when
+// ctx is done. See Env.EvalContext.
+func (rt *Runtime) EvalReaderContext(ctx context.Context, r io.Reader) (Value, error) {
+	return rt.EvalSourceContext(ctx, "", r)
+}
+
+// EvalSource is the same as EvalReader but reports errors positions using the
+// given source name (e.g. a file path).
+func (rt *Runtime) EvalSource(source string, r io.Reader) (Value, error) {
+	return rt.EvalSourceContext(context.Background(), source, r)
+}
+
+// EvalSourceContext is the same as EvalSource but evaluation is aborted when
+// ctx is done. Step budget is shared by all expressions read from r. See
+// Env.EvalContext.
+func (rt *Runtime) EvalSourceContext(ctx context.Context, source string, r io.Reader) (result Value, err error) {
+	parser := NewParser(r)
+	parser.SetSource(source)
+
+	rt.env.withLimits(ctx, rt.limits, func() {
+		for {
+			value, parseErr := parser.Parse()
+			if parseErr.Cause != nil {
+				if parseErr.Cause == io.EOF {
+					return
+				}
+				result, err = nil, parseErr
+				return
+			}
+
+			result = rt.env.Eval(value)
+			if evalErr, isErr := result.(error); isErr {
+				result, err = nil, evalErr
+				return
+			}
+		}
+	})
+
+	return result, err
+}
@@ -0,0 +1,250 @@
+package tabp
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuntime(t *testing.T) {
+	t.Run("KeepState", func(t *testing.T) {
+		rt := NewRuntime()
+
+		_, err := rt.EvalString(`(defvar counter 0) (defun incr () (setq counter (add counter 1)))`)
+		require.NoError(t, err)
+
+		for i := 1; i <= 3; i++ {
+			v, err := rt.EvalString(`(incr)`)
+			require.NoError(t, err)
+			require.Equal(t, i, v)
+		}
+
+		v, err := rt.EvalReader(bytes.NewBufferString(`(incr) counter`))
+		require.NoError(t, err)
+		require.Equal(t, 4, v)
+	})
+
+	t.Run("EvalValue", func(t *testing.T) {
+		rt := NewRuntime()
+
+		var expr Table
+		expr.Append(Symbol("ADD"))
+		expr.Append(1)
+		expr.Append(2)
+
+		v, err := rt.EvalValue(&expr)
+		require.NoError(t, err)
+		require.Equal(t, 3, v)
+	})
+
+	t.Run("ParsedTable", func(t *testing.T) {
+		rt := NewRuntime()
+
+		v, err := rt.EvalString(`'(1 (2 3))`)
+		require.NoError(t, err)
+		require.True(t, v.(*Table).Position().IsValid())
+
+		var nested, expected Table
+		nested.Append(2)
+		nested.Append(3)
+		expected.Append(1)
+		expected.Append(&nested)
+
+		require.NotEqual(t, &expected, v)
+		require.True(t, ValuesEqual(&expected, v))
+	})
+
+	t.Run("HostFunction", func(t *testing.T) {
+		rt := NewRuntime()
+		rt.Env().Defun("DOUBLE", func(_ *Env, tab ReadOnlyTable) Value {
+			return tab.Get(1).(int) * 2
+		})
+
+		v, err := rt.EvalString(`(double 21)`)
+		require.NoError(t, err)
+		require.Equal(t, 42, v)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		rt := NewRuntime()
+
+		_, err := rt.EvalString(`(div 1 0)`)
+		require.ErrorIs(t, err, Error("division by zero"))
+
+		var evalErr EvalError
+		require.ErrorAs(t, err, &evalErr)
+		require.Equal(t, 1, evalErr.Position.line)
+
+		// Runtime is still usable after an error.
+		v, err := rt.EvalString(`(add 1 1)`)
+		require.NoError(t, err)
+		require.Equal(t, 2, v)
+
+		_, err = rt.EvalString(`(add 1`)
+		require.Error(t, err)
+	})
+	t.Run("IO", func(t *testing.T) {
+		newRuntime := func(stdin string) (*Runtime, *bytes.Buffer, *bytes.Buffer) {
+			var stdout, stderr bytes.Buffer
+			rt := NewRuntime()
+			rt.Env().SetStdout(&stdout)
+			rt.Env().SetStderr(&stderr)
+			rt.Env().SetStdin(bytes.NewBufferString(stdin))
+
+			return rt, &stdout, &stderr
+		}
+
+		rt1, stdout1, stderr1 := newRuntime("")
+		rt2, stdout2, _ := newRuntime("")
+
+		_, err := rt1.EvalString(`
+			(printf "%v-%v\n" 1 "a")
+			(print "x" 'y '(1 2))
+			(println)
+			(println "done")
+			(eprintf "oops %v" 2)`)
+		require.NoError(t, err)
+
+		_, err = rt2.EvalString(`(defun greet () (println "hi")) (greet)`)
+		require.NoError(t, err)
+
+		require.Equal(t, "1-\"a\"\nx Y (1 2)\ndone\n", stdout1.String())
+		require.Equal(t, "oops 2", stderr1.String())
+		require.Equal(t, "hi\n", stdout2.String())
+
+		rt, _, _ := newRuntime("first line\r\nsecond\nlast")
+		v, err := rt.EvalString(`(table (read-line) (read-line) (read-line) (nilp (read-line)))`)
+		require.NoError(t, err)
+		require.Equal(t, `("first line" "second" "last" true)`, Sexpr(v))
+	})
+	t.Run("Limits", func(t *testing.T) {
+		t.Run("Steps", func(t *testing.T) {
+			rt := NewRuntime()
+			rt.SetLimits(Limits{MaxSteps: 10_000})
+
+			_, err := rt.EvalString(`(defun f () (f)) (f)`)
+			require.ErrorIs(t, err, ErrStepLimit)
+
+			_, err = rt.EvalString("(defmacro m () `(m)) (macroexpand '(m))")
+			require.ErrorIs(t, err, ErrStepLimit)
+
+			// Budget is reset on each evaluation.
+			v, err := rt.EvalString(`(add 1 2)`)
+			require.NoError(t, err)
+			require.Equal(t, 3, v)
+		})
+
+		t.Run("Depth", func(t *testing.T) {
+			rt := NewRuntime()
+			rt.SetLimits(Limits{MaxDepth: 100})
+
+			_, err := rt.EvalString(`
+				(defun count (n) (if (eq n 0) 0 (add 1 (count (sub n 1)))))
+				(count 1000000)`)
+			require.ErrorIs(t, err, ErrDepthLimit)
+
+			// Tail calls don't increase depth.
+			v, err := rt.EvalString(`
+				(defun loop (n) (if (eq n 0) 'done (loop (sub n 1))))
+				(loop 1000)`)
+			require.NoError(t, err)
+			require.Equal(t, Symbol("DONE"), v)
+		})
+
+		t.Run("RecursiveMacro", func(t *testing.T) {
+			tcases := []string{
+				"(defmacro m () `(add 1 (m))) (m)",
+				"(defmacro m () `(try (m))) (m)",
+			}
+			for _, program := range tcases {
+				t.Run(program, func(t *testing.T) {
+					rt := NewSandboxRuntime()
+					rt.SetLimits(Limits{MaxDepth: 200})
+
+					_, err := rt.EvalString(program)
+					require.ErrorIs(t, err, ErrDepthLimit)
+				})
+			}
+		})
+
+		t.Run("Context", func(t *testing.T) {
+			rt := NewRuntime()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			_, err := rt.EvalStringContext(ctx, `(while t 1)`)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+
+			var evalErr EvalError
+			require.ErrorAs(t, err, &evalErr)
+		})
+
+		t.Run("Uncatchable", func(t *testing.T) {
+			rt := NewRuntime()
+			rt.SetLimits(Limits{MaxSteps: 1000})
+
+			_, err := rt.EvalString(`
+				(defvar cleaned nil)
+				(try (while t 1) (catch err 'caught) (finally (setq cleaned t)))`)
+			require.ErrorIs(t, err, ErrStepLimit)
+
+			v, err := rt.EvalString(`cleaned`)
+			require.NoError(t, err)
+			require.Nil(t, v)
+		})
+
+		t.Run("Memory", func(t *testing.T) {
+			tcases := []string{
+				`(defvar tab (table)) (while t (append tab 1))`,
+				`(defvar tab '()) (dotimes (i 1000000) (set tab (to-string i) i))`,
+				`(defvar str "") (while t (setq str (sprintf "%v%v" str "abcdef")))`,
+				`(replace "aaaaaaaaaa" "a" (concat "b" "bbbbbbbbbbbbbbbbbbbbbbbbb"))`,
+				`(defvar str "") (try (while t (setq str (concat "a" "b"))) (catch err 'caught))`,
+				`(defun f (&rest r) r) (defvar x (table)) (dotimes (i 10000) (setq x (apply 'f 1 x)))`,
+				`(defvar x (table)) (dotimes (i 10000) (setq x (apply 'table 1 x)))`,
+				`(defun f (&rest r) r) (dotimes (i 10000) (funcall 'f 1 2 3))`,
+			}
+			for _, program := range tcases {
+				t.Run(program, func(t *testing.T) {
+					rt := NewSandboxRuntime()
+					rt.SetLimits(Limits{MaxBytes: 256})
+
+					_, err := rt.EvalString(program)
+					require.ErrorIs(t, err, ErrMemoryLimit)
+				})
+			}
+
+			t.Run("WithinQuota", func(t *testing.T) {
+				rt := NewSandboxRuntime()
+				rt.SetLimits(Limits{MaxBytes: 1024})
+
+				for i := 0; i < 10; i++ {
+					v, err := rt.EvalString(`(len (table 1 2 3 (concat "a" "b")))`)
+					require.NoError(t, err)
+					require.Equal(t, 4, v)
+				}
+			})
+
+			t.Run("HostAlloc", func(t *testing.T) {
+				rt := NewSandboxRuntime()
+				rt.SetLimits(Limits{MaxBytes: 1024})
+				rt.Env().Defun("ALLOC", func(env *Env, tab ReadOnlyTable) Value {
+					if err := env.Alloc(tab.Get(1).(int)); err != nil {
+						return err
+					}
+					return true
+				})
+
+				_, err := rt.EvalString(`(alloc 1000)`)
+				require.NoError(t, err)
+
+				_, err = rt.EvalString(`(alloc 1000) (alloc 1000)`)
+				require.ErrorIs(t, err, ErrMemoryLimit)
+			})
+		})
+	})
+}
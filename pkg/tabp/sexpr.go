@@ -27,7 +27,7 @@ func Sexpr(v any) string {
 	case string:
 		return strconv.Quote(value)
 
-	case EvalError:
+	case error:
 		return value.Error()
 
 	default:
@@ -0,0 +1,169 @@
+package tabp
+
+// Module define a named group of standard library builtins that can be
+// installed in an environment.
+type Module struct {
+	Name    string
+	Install func(env *Env)
+}
+
+var (
+	// CoreModule contains variables, special forms (DEFUN, IF, LET, TRY...),
+	// functions, errors and types builtins. Other modules depend on it.
+	CoreModule = Module{Name: "core", Install: installCore}
+	// MathModule contains arithmetic and comparison functions.
+	MathModule = Module{Name: "math", Install: installMath}
+	// StringsModule contains string functions.
+	StringsModule = Module{Name: "strings", Install: installStrings}
+	// TablesModule contains table functions.
+	TablesModule = Module{Name: "tables", Install: installTables}
+	// IOModule contains functions performing I/O.
+	IOModule = Module{Name: "io", Install: installIO}
+	// OSModule contains functions accessing operating system.
+	OSModule = Module{Name: "os", Install: installOS}
+)
+
+var (
+	// StdModules contains all standard library modules.
+	StdModules = []Module{CoreModule, MathModule, StringsModule, TablesModule, IOModule, OSModule}
+	// SandboxModules contains standard library modules without any I/O or
+	// operating system access.
+	SandboxModules = []Module{CoreModule, MathModule, StringsModule, TablesModule}
+)
+
+// LookupModule returns standard library module with the given name.
+func LookupModule(name string) (Module, bool) {
+	for _, m := range StdModules {
+		if m.Name == name {
+			return m, true
+		}
+	}
+
+	return Module{}, false
+}
+
+func installCore(env *Env) {
+	// Variables.
+	env.Defvar("TABP-VERSION", "0.1.0")
+	env.Defvar("T", true)
+	env.Defvar("TRUE", true)
+	env.Defvar("FALSE", false)
+	env.Defvar("NIL", nil)
+
+	// Macros.
+	env.Defmacro("QUOTE", macroQuote)
+	env.Defmacro("QUASIQUOTE", macroQuasiQuote)
+	env.Defmacro("DEFUN", macroDefun)
+	env.Defmacro("LAMBDA", macroLambda)
+	env.Defmacro("DEFMACRO", macroDefmacro)
+	env.Defmacro("DEFVAR", macroDefvar)
+	env.Defmacro("SETQ", macroSetq)
+	env.Defmacro("SET!", macroSetq)
+	env.Defmacro("IF", macroIf)
+	env.Defmacro("WHEN", macroWhen)
+	env.Defmacro("UNLESS", macroUnless)
+	env.Defmacro("COND", macroCond)
+	env.Defmacro("CASE", macroCase)
+	env.Defmacro("TRY", macroTry)
+	env.Defmacro("AND", macroAnd)
+	env.Defmacro("OR", macroOr)
+	env.Defmacro("PROGN", macroProgn)
+	env.Defmacro("WHILE", macroWhile)
+	env.Defmacro("DOTIMES", macroDotimes)
+	env.Defmacro("FOREACH", macroForeach)
+	env.Defmacro("FOREACH-SEQ", macroForeachSeq)
+	env.Defmacro("FOREACH-KVS", macroForeachKVs)
+	env.Defmacro("LET", macroLet)
+	env.Defmacro("LET*", macroLetStar)
+
+	// Functions.
+	env.Defun("EQ", fnEq)
+	env.Defun("NOT", fnNot)
+	env.Defun("FUNCALL", fnFuncall)
+	env.Defun("APPLY", fnApply)
+	env.Defun("MACROEXPAND-1", fnMacroExpand1)
+	env.Defun("MACROEXPAND", fnMacroExpand)
+
+	// Errors.
+	env.Defun("THROW", fnThrow)
+	env.Defun("ERROR-MESSAGE", fnErrorMessage)
+	env.Defun("ERROR-DATA", fnErrorData)
+
+	// Types.
+	env.Defun("TYPE-OF", fnTypeOf)
+	env.Defun("TABLEP", typePredicate("TABLE"))
+	env.Defun("SYMBOLP", typePredicate("SYMBOL"))
+	env.Defun("STRINGP", typePredicate("STRING"))
+	env.Defun("NUMBERP", typePredicate("INTEGER", "FLOAT"))
+	env.Defun("INTEGERP", typePredicate("INTEGER"))
+	env.Defun("FLOATP", typePredicate("FLOAT"))
+	env.Defun("FUNCTIONP", typePredicate("FUNCTION"))
+	env.Defun("NILP", typePredicate("NIL"))
+	env.Defun("TO-INT", fnToInt)
+	env.Defun("TO-FLOAT", fnToFloat)
+	env.Defun("TO-STRING", fnToString)
+	env.Defun("PARSE-NUMBER", fnParseNumber)
+}
+
+func installMath(env *Env) {
+	env.Defun("ADD", fnAdd)
+	env.Defun("SUB", fnSub)
+	env.Defun("MUL", fnMul)
+	env.Defun("DIV", fnDiv)
+	env.Defun("MOD", fnMod)
+	env.Defun("POW", fnPow)
+	env.Defun("NEG", fnNeg)
+	env.Defun("ABS", fnAbs)
+	env.Defun("MIN", fnMin)
+	env.Defun("MAX", fnMax)
+	env.Defun("LT", fnLt)
+	env.Defun("LE", fnLe)
+	env.Defun("GT", fnGt)
+	env.Defun("GE", fnGe)
+}
+
+func installStrings(env *Env) {
+	env.Defun("CONCAT", fnConcat)
+	env.Defun("STRLEN", fnStrlen)
+	env.Defun("SUBSTR", fnSubstr)
+	env.Defun("SPLIT", fnSplit)
+	env.Defun("JOIN", fnJoin)
+	env.Defun("UPCASE", fnUpcase)
+	env.Defun("DOWNCASE", fnDowncase)
+	env.Defun("TRIM", fnTrim)
+	env.Defun("CONTAINS", fnContains)
+	env.Defun("INDEX", fnIndex)
+	env.Defun("REPLACE", fnReplace)
+	env.Defun("STARTS-WITH", fnStartsWith)
+	env.Defun("ENDS-WITH", fnEndsWith)
+	env.Defun("STRING-TO-SYMBOL", fnStringToSymbol)
+	env.Defun("SYMBOL-TO-STRING", fnSymbolToString)
+	env.Defun("SPRINTF", fnSprintf)
+}
+
+func installTables(env *Env) {
+	env.Defun("TABLE", fnTable)
+	env.Defun("GET", fnGet)
+	env.Defun("SET", fnSet)
+	env.Defun("HAS", fnHas)
+	env.Defun("APPEND", fnAppend)
+	env.Defun("INSERT", fnInsert)
+	env.Defun("DELETE", fnDelete)
+	env.Defun("LEN", fnLen)
+	env.Defun("SEQLEN", fnSeqLen)
+	env.Defun("KVSLEN", fnKVsLen)
+	env.Defun("KEYS", fnKeys)
+	env.Defun("VALUES", fnValues)
+}
+
+func installIO(env *Env) {
+	env.Defun("PRINTF", fnPrintf)
+	env.Defun("EPRINTF", fnEprintf)
+	env.Defun("PRINT", fnPrint)
+	env.Defun("PRINTLN", fnPrintln)
+	env.Defun("READ-LINE", fnReadLine)
+}
+
+func installOS(env *Env) {
+	env.Defun("GETENV", fnGetenv)
+}
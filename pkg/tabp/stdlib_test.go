@@ -0,0 +1,57 @@
+package tabp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStdlib(t *testing.T) {
+	t.Run("Sandbox", func(t *testing.T) {
+		rt := NewSandboxRuntime()
+
+		v, err := rt.EvalString(`(concat "a" (to-string (add 1 2)))`)
+		require.NoError(t, err)
+		require.Equal(t, "a3", v)
+
+		for _, program := range []string{`(printf "hello")`, `(getenv "HOME")`} {
+			_, err = rt.EvalString(program)
+			require.ErrorIs(t, err, Error("function not found"))
+		}
+	})
+
+	t.Run("Modules", func(t *testing.T) {
+		rt := NewModulesRuntime(CoreModule, TablesModule)
+
+		v, err := rt.EvalString(`(len (table 1 2 3))`)
+		require.NoError(t, err)
+		require.Equal(t, 3, v)
+
+		_, err = rt.EvalString(`(add 1 2)`)
+		require.ErrorIs(t, err, Error("function not found"))
+	})
+
+	t.Run("LookupModule", func(t *testing.T) {
+		for _, name := range []string{"core", "math", "strings", "tables", "io", "os"} {
+			m, ok := LookupModule(name)
+			require.True(t, ok)
+			require.Equal(t, name, m.Name)
+		}
+
+		_, ok := LookupModule("network")
+		require.False(t, ok)
+	})
+
+	t.Run("Getenv", func(t *testing.T) {
+		t.Setenv("TABP_TEST_VAR", "foo")
+
+		rt := NewRuntime()
+		v, err := rt.EvalString(`(getenv "TABP_TEST_VAR")`)
+		require.NoError(t, err)
+		require.Equal(t, "foo", v)
+
+		v, err = rt.EvalString(`(getenv "TABP_TEST_UNSET_VAR")`)
+		require.NoError(t, err)
+		require.Nil(t, v)
+	})
+}
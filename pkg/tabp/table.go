@@ -26,9 +26,21 @@ type ReadOnlyTable interface {
 // time. All values are stored in map except values that are part of the
 // sequence. Entries with an integer key 'n' are stored in the slice (and part
 // of the sequence) if for i from 0 to n tab.Get(i) is not nil.
+//
+// Tables returned by the parser also record their source position. Two tables
+// with the same content but different positions are therefore not
+// reflect.DeepEqual, use ValuesEqual to compare them.
 type Table struct {
 	kv  map[Value]TableEntry
 	seq []Value
+	// Source position of parsed tables, ignored by equality.
+	pos Position
+}
+
+// Position returns position of table in its source code. An invalid position
+// is returned if table wasn't parsed.
+func (mt *Table) Position() Position {
+	return mt.pos
 }
 
 // TableEntry define an entry in a Table.
@@ -291,15 +303,35 @@ func (mt *Table) Len() int {
 	return len(mt.seq) + len(mt.kv)
 }
 
-// ToSExpr implements SExpr.
+// ToSExpr implements SExpr. Tables containing themselves are written as
+// #<CYCLE>.
 func (mt *Table) ToSExpr() string {
+	return mt.toSExpr(map[*Table]bool{})
+}
+
+// toSExpr returns S-Expression of table. Visiting contains tables being
+// written so cycles are detected.
+func (mt *Table) toSExpr(visiting map[*Table]bool) string {
+	if visiting[mt] {
+		return "#<CYCLE>"
+	}
+	visiting[mt] = true
+	defer delete(visiting, mt)
+
+	sexpr := func(v Value) string {
+		if tab, isTable := v.(*Table); isTable && tab != nil {
+			return tab.toSExpr(visiting)
+		}
+		return Sexpr(v)
+	}
+
 	var result strings.Builder
 	result.WriteRune('(')
 
 	totalKeys := len(mt.seq) + len(mt.kv)
 
 	for k, value := range mt.seq {
-		result.WriteString(Sexpr(value))
+		result.WriteString(sexpr(value))
 		if k < totalKeys-1 {
 			result.WriteRune(' ')
 		}
@@ -307,9 +339,9 @@ func (mt *Table) ToSExpr() string {
 
 	i := len(mt.seq)
 	for _, entry := range mt.kv {
-		result.WriteString(Sexpr(entry.Key))
+		result.WriteString(sexpr(entry.Key))
 		result.WriteString(": ")
-		result.WriteString(Sexpr(entry.Value))
+		result.WriteString(sexpr(entry.Value))
 		if i < totalKeys-1 {
 			result.WriteRune(' ')
 		}
@@ -1,5 +1,10 @@
 package tabp
 
+import (
+	"math"
+	"reflect"
+)
+
 // Value define a Tabp value.
 type Value any
 
@@ -11,6 +16,17 @@ func (s Symbol) ToSExpr() string {
 	return string(s)
 }
 
+// isConstant returns whether s is a constant symbol (T, NIL, TRUE and FALSE)
+// that can't be assigned or rebound by tabp code.
+func isConstant(s Symbol) bool {
+	switch s {
+	case "T", "NIL", "TRUE", "FALSE":
+		return true
+	default:
+		return false
+	}
+}
+
 // Error define an error message.
 type Error string
 
@@ -24,35 +40,135 @@ func (e Error) ToSExpr() string {
 	return string(e)
 }
 
-func toNumber(x Value) (int, float64, bool) {
+// isTruthy returns whether v is considered true by conditionals. Only nil and
+// false are false.
+func isTruthy(v Value) bool {
+	return v != nil && v != false
+}
+
+// number define a tabp number, either an integer or a float.
+type number struct {
+	int     int
+	float   float64
+	isFloat bool
+}
+
+// toFloat returns number as a float.
+func (n number) toFloat() float64 {
+	if n.isFloat {
+		return n.float
+	}
+
+	return float64(n.int)
+}
+
+// value returns number as an int or float64 Value.
+func (n number) value() Value {
+	if n.isFloat {
+		return n.float
+	}
+
+	return n.int
+}
+
+// boolValue returns true if b is true and nil otherwise.
+func boolValue(b bool) Value {
+	if b {
+		return true
+	}
+
+	return nil
+}
+
+// toNumber converts any Go integer or float to a number. An error is returned
+// if x isn't a number or if it is an unsigned integer that overflows int.
+func toNumber(x Value) (number, error) {
 	switch value := x.(type) {
 	case int:
-		return value, 0.0, true
+		return number{int: value}, nil
 	case int8:
-		return int(value), 0.0, true
+		return number{int: int(value)}, nil
 	case int16:
-		return int(value), 0.0, true
+		return number{int: int(value)}, nil
 	case int32:
-		return int(value), 0.0, true
+		return number{int: int(value)}, nil
 	case int64:
-		return int(value), 0.0, true
+		if value > math.MaxInt || value < math.MinInt {
+			return number{}, Error("integer overflow")
+		}
+		return number{int: int(value)}, nil
 	case uint:
-		return int(value), 0.0, true
+		if uint64(value) > math.MaxInt {
+			return number{}, Error("integer overflow")
+		}
+		return number{int: int(value)}, nil
 	case uint8:
-		return int(value), 0.0, true
+		return number{int: int(value)}, nil
 	case uint16:
-		return int(value), 0.0, true
+		return number{int: int(value)}, nil
 	case uint32:
-		return int(value), 0.0, true
+		if uint64(value) > math.MaxInt {
+			return number{}, Error("integer overflow")
+		}
+		return number{int: int(value)}, nil
 	case uint64:
-		return int(value), 0.0, true
+		if value > math.MaxInt {
+			return number{}, Error("integer overflow")
+		}
+		return number{int: int(value)}, nil
 
 	case float32:
-		return 0, float64(value), true
+		return number{float: float64(value), isFloat: true}, nil
 	case float64:
-		return 0, value, true
+		return number{float: value, isFloat: true}, nil
 
 	default:
-		return 0, 0, false
+		return number{}, Error("value is not a number")
 	}
 }
+
+// ValuesEqual reports whether a and b are deeply equal. Unlike
+// reflect.DeepEqual, tables are compared by content only so their source
+// position is ignored. Tables containing themselves are supported. Go hosts
+// should use it to compare tables returned by the runtime as parsed tables
+// aren't reflect.DeepEqual to tables built by hand.
+func ValuesEqual(a, b Value) bool {
+	return tablesEqual(a, b, map[[2]*Table]bool{})
+}
+
+// tablesEqual is the same as ValuesEqual. Compared contains pairs of tables
+// being compared, they are assumed equal so cycles terminate.
+func tablesEqual(a, b Value, compared map[[2]*Table]bool) bool {
+	tabA, aIsTable := a.(*Table)
+	tabB, bIsTable := b.(*Table)
+	if !aIsTable || !bIsTable {
+		return reflect.DeepEqual(a, b)
+	}
+
+	if tabA == tabB {
+		return true
+	}
+	if tabA == nil || tabB == nil || tabA.SeqLen() != tabB.SeqLen() || tabA.KVsLen() != tabB.KVsLen() {
+		return false
+	}
+
+	pair := [2]*Table{tabA, tabB}
+	if compared[pair] {
+		return true
+	}
+	compared[pair] = true
+
+	for i, v := range tabA.seq {
+		if !tablesEqual(v, tabB.seq[i], compared) {
+			return false
+		}
+	}
+	for k, entry := range tabA.kv {
+		other, ok := tabB.kv[k]
+		if !ok || !tablesEqual(entry.Value, other.Value, compared) {
+			return false
+		}
+	}
+
+	return true
+}